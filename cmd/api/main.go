@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/EricFreesoul/phoenix-feuer-os/internal/ai/claude"
 	"github.com/EricFreesoul/phoenix-feuer-os/internal/ai/openai"
@@ -31,6 +30,26 @@ func main() {
 		cfg.SEO.CrawlTimeout,
 		cfg.SEO.MaxCrawlDepth,
 	)
+	crawlerInst.SetRespectRobotsTxt(cfg.SEO.RespectRobotsTxt)
+	crawlerInst.SetMaxConcurrent(cfg.SEO.MaxConcurrentCrawls)
+	crawlerInst.SetCrawlDelay(cfg.SEO.CrawlDelay)
+	crawlerInst.SetMaxRetries(cfg.SEO.MaxRetries)
+	crawlerInst.SetMobileParity(cfg.SEO.MobileParity)
+	crawlerInst.SetCheckAssets(cfg.SEO.CheckAssets)
+	crawlerInst.SetSoft404Probe(cfg.SEO.Soft404Probe)
+	crawlerInst.SetCheckpointDir(cfg.SEO.CheckpointDir)
+	if err := crawlerInst.SetEgress(crawler.Egress{
+		Proxy:               cfg.SEO.ProxyURL,
+		Proxies:             cfg.SEO.Proxies,
+		DNSServer:           cfg.SEO.DNSServer,
+		SourceIP:            cfg.SEO.SourceIP,
+		MaxConnsPerHost:     cfg.SEO.MaxConnsPerHost,
+		MaxIdleConnsPerHost: cfg.SEO.MaxIdleConnsPerHost,
+		CAFile:              cfg.SEO.TLSCAFile,
+		InsecureSkipVerify:  cfg.SEO.TLSInsecure,
+	}); err != nil {
+		log.Fatalf("Failed to configure crawler egress: %v", err)
+	}
 
 	// Initialize AI clients
 	var claudeClient *claude.Client
@@ -52,6 +71,7 @@ func main() {
 
 	// Initialize handlers
 	seoHandler := handlers.NewSEOHandler(crawlerInst, claudeClient, openaiClient)
+	seoHandler.SetAllowInsecureTLS(cfg.SEO.AllowInsecureTLS)
 
 	// Setup routes
 	handler := routes.Setup(seoHandler, cfg.Server.AllowedOrigins)
@@ -3,7 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/EricFreesoul/phoenix-feuer-os/internal/ai/claude"
@@ -14,25 +20,46 @@ import (
 
 // SEOHandler handles SEO-related API endpoints
 type SEOHandler struct {
-	crawler     *crawler.Crawler
+	crawler      *crawler.Crawler
 	claudeClient *claude.Client
 	openaiClient *openai.Client
+	// allowInsecureTLS lets requests turn off certificate verification
+	allowInsecureTLS bool
 }
 
 // NewSEOHandler creates a new SEO handler
 func NewSEOHandler(crawlerInst *crawler.Crawler, claudeClient *claude.Client, openaiClient *openai.Client) *SEOHandler {
 	return &SEOHandler{
-		crawler:     crawlerInst,
+		crawler:      crawlerInst,
 		claudeClient: claudeClient,
 		openaiClient: openaiClient,
 	}
 }
 
+// SetAllowInsecureTLS controls whether crawl requests may set insecure_tls
+// to accept invalid certificates of the crawled host
+func (h *SEOHandler) SetAllowInsecureTLS(allow bool) {
+	h.allowInsecureTLS = allow
+}
+
 // AnalyzeURLRequest represents a request to analyze a URL
 type AnalyzeURLRequest struct {
-	URL      string   `json:"url"`
-	Keywords []string `json:"keywords,omitempty"`
-	UseAI    bool     `json:"use_ai"`
+	URL      string              `json:"url"`
+	Keywords []string            `json:"keywords,omitempty"`
+	UseAI    bool                `json:"use_ai"`
+	Access   *CrawlAccessRequest `json:"access,omitempty"`
+}
+
+// CrawlAccessRequest holds credentials for crawling protected sites such as
+// staging environments. They are only sent to the host of the crawled URL.
+type CrawlAccessRequest struct {
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	Cookies     map[string]string `json:"cookies,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Resolve     map[string]string `json:"resolve,omitempty"`      // host or host:port to IP address
+	Proxy       string            `json:"proxy,omitempty"`        // name of a proxy from SEO_PROXIES
+	InsecureTLS bool              `json:"insecure_tls,omitempty"` // requires SEO_ALLOW_INSECURE_TLS
 }
 
 // AnalyzeURLResponse represents the response of URL analysis
@@ -56,11 +83,18 @@ func (h *SEOHandler) AnalyzeURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pageCrawler, release, err := h.crawlerFor(req.URL, req.Access)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer release()
+
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
 	// Crawl the URL
-	crawlResult, err := h.crawler.CrawlPage(ctx, req.URL)
+	crawlResult, err := pageCrawler.CrawlPage(ctx, req.URL)
 	if err != nil {
 		http.Error(w, "Failed to crawl URL: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -82,6 +116,7 @@ func (h *SEOHandler) AnalyzeURL(w http.ResponseWriter, r *http.Request) {
 			"url":        req.URL,
 			"score":      seoScore,
 			"crawl_data": crawlResult,
+			"outline":    crawlResult.Outline(),
 		}
 
 		insights, err := h.claudeClient.AnalyzeSEO(ctx, seoData)
@@ -168,6 +203,246 @@ func (h *SEOHandler) OptimizeMeta(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metaTags)
 }
 
+// Site crawl limits. A site crawl takes longer than the server write
+// timeout, so the handlers extend the deadline of their own response.
+const (
+	siteCrawlTimeout      = 5 * time.Minute
+	defaultSiteCrawlPages = 200
+	maxSiteCrawlPages     = 2000
+)
+
+// SiteCrawlRequest represents a request to crawl a whole site
+type SiteCrawlRequest struct {
+	URL             string              `json:"url"`
+	CrawlID         string              `json:"crawl_id,omitempty"`          // resumes a checkpointed crawl
+	PreviousCrawlID string              `json:"previous_crawl_id,omitempty"` // re-crawls conditionally against an earlier crawl
+	MaxPages        int                 `json:"max_pages,omitempty"`
+	MaxDepth        int                 `json:"max_depth,omitempty"`
+	UseSitemaps     bool                `json:"use_sitemaps"`
+	CheckLinks      bool                `json:"check_links"` // verifies all links and images of the crawled pages
+	Access          *CrawlAccessRequest `json:"access,omitempty"`
+}
+
+// SiteCrawlResponse represents the site-level results of a site crawl
+type SiteCrawlResponse struct {
+	CrawlID        string                   `json:"crawl_id,omitempty"`
+	StartURL       string                   `json:"start_url"`
+	Pages          int                      `json:"pages"`
+	Failed         []crawler.FailedURL      `json:"failed"`
+	Skipped        map[string]int           `json:"skipped,omitempty"`
+	Changes        *crawler.CrawlChanges    `json:"changes,omitempty"`
+	LinkCheck      *crawler.LinkCheckReport `json:"link_check,omitempty"`
+	SitemapIssues  []crawler.SitemapIssue   `json:"sitemap_issues,omitempty"`
+	HreflangIssues []crawler.HreflangIssue  `json:"hreflang_issues,omitempty"`
+	Duplicates     *crawler.DuplicateReport `json:"duplicates,omitempty"`
+	Errors         []string                 `json:"errors,omitempty"`
+	CrawledAt      time.Time                `json:"crawled_at"`
+}
+
+// SiteCrawl handles POST /api/v1/seo/site/crawl
+func (h *SEOHandler) SiteCrawl(w http.ResponseWriter, r *http.Request) {
+	var req SiteCrawlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	site := h.crawlSite(w, r, req)
+	if site == nil {
+		return
+	}
+
+	response := SiteCrawlResponse{
+		CrawlID:        site.CrawlID,
+		StartURL:       site.StartURL,
+		Pages:          len(site.Pages),
+		Failed:         site.Failed,
+		Skipped:        site.Skipped,
+		Changes:        site.Changes,
+		LinkCheck:      site.LinkCheck,
+		SitemapIssues:  site.SitemapIssues,
+		HreflangIssues: site.HreflangIssues,
+		Duplicates:     site.Duplicates,
+		Errors:         site.Errors,
+		CrawledAt:      time.Now(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SiteGraphRequest represents a request for the internal link graph of a
+// site. The link check is not part of the graph, so check_links is ignored.
+type SiteGraphRequest struct {
+	SiteCrawlRequest
+	Format string `json:"format,omitempty"` // json (default), graphml or dot
+}
+
+// SiteGraphResponse represents the link graph of a site crawl
+type SiteGraphResponse struct {
+	CrawlID   string              `json:"crawl_id,omitempty"`
+	StartURL  string              `json:"start_url"`
+	Pages     int                 `json:"pages"`
+	Failed    []crawler.FailedURL `json:"failed"`
+	Graph     *crawler.LinkGraph  `json:"graph"`
+	Errors    []string            `json:"errors,omitempty"`
+	CrawledAt time.Time           `json:"crawled_at"`
+}
+
+// SiteGraph handles POST /api/v1/seo/site/graph
+func (h *SEOHandler) SiteGraph(w http.ResponseWriter, r *http.Request) {
+	var req SiteGraphRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Format {
+	case "", "json", "graphml", "dot":
+	default:
+		http.Error(w, "Format must be json, graphml or dot", http.StatusBadRequest)
+		return
+	}
+
+	req.CheckLinks = false
+	site := h.crawlSite(w, r, req.SiteCrawlRequest)
+	if site == nil {
+		return
+	}
+
+	// The status is already sent, so a failed export can only be logged
+	var err error
+	switch req.Format {
+	case "graphml":
+		w.Header().Set("Content-Type", "application/graphml+xml")
+		w.Header().Set("Content-Disposition", `attachment; filename="site-graph.graphml"`)
+		err = site.LinkGraph.WriteGraphML(w)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Header().Set("Content-Disposition", `attachment; filename="site-graph.dot"`)
+		err = site.LinkGraph.WriteDOT(w)
+	default:
+		response := SiteGraphResponse{
+			CrawlID:   site.CrawlID,
+			StartURL:  site.StartURL,
+			Pages:     len(site.Pages),
+			Failed:    site.Failed,
+			Graph:     site.LinkGraph,
+			Errors:    site.Errors,
+			CrawledAt: time.Now(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(response)
+	}
+	if err != nil {
+		log.Printf("Failed to write the site graph of %s: %v", req.URL, err)
+	}
+}
+
+// crawlSite crawls the site of a request. If the crawl fails, it writes the
+// error response and returns nil. A crawl that stopped early is returned
+// with the reason in its Errors.
+func (h *SEOHandler) crawlSite(w http.ResponseWriter, r *http.Request, req SiteCrawlRequest) *crawler.SiteCrawlResult {
+	if req.URL == "" {
+		http.Error(w, "URL is required", http.StatusBadRequest)
+		return nil
+	}
+
+	siteCrawler, release, err := h.crawlerFor(req.URL, req.Access)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	defer release()
+
+	if req.MaxPages <= 0 {
+		req.MaxPages = defaultSiteCrawlPages
+	}
+	if req.MaxPages > maxSiteCrawlPages {
+		req.MaxPages = maxSiteCrawlPages
+	}
+
+	// Ignore the error, the default deadline applies if it cannot be changed
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(siteCrawlTimeout + 30*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), siteCrawlTimeout)
+	defer cancel()
+
+	site, err := siteCrawler.CrawlSite(ctx, req.URL, crawler.SiteCrawlOptions{
+		MaxPages:        req.MaxPages,
+		MaxDepth:        req.MaxDepth,
+		UseSitemaps:     req.UseSitemaps,
+		CheckLinks:      req.CheckLinks,
+		CrawlID:         req.CrawlID,
+		PreviousCrawlID: req.PreviousCrawlID,
+	})
+	if site == nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, crawler.ErrCrawlRunning):
+			status = http.StatusConflict
+		case errors.Is(err, crawler.ErrCrawlMismatch):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, "Failed to crawl site: "+err.Error(), status)
+		return nil
+	}
+	if err != nil {
+		// Partial crawl, the results cover the pages crawled so far
+		site.Errors = append(site.Errors, "crawl stopped early: "+err.Error())
+	}
+
+	if site.CrawlID != "" {
+		w.Header().Set("X-Crawl-ID", site.CrawlID)
+	}
+	return site
+}
+
+// crawlerFor returns the crawler for a URL, authenticated for its host if
+// access is given. release must be called once the crawl is done.
+func (h *SEOHandler) crawlerFor(pageURL string, access *CrawlAccessRequest) (*crawler.Crawler, func(), error) {
+	if access == nil {
+		return h.crawler, func() {}, nil
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Hostname() == "" {
+		return nil, nil, fmt.Errorf("invalid URL %q", pageURL)
+	}
+	if access.InsecureTLS && !h.allowInsecureTLS {
+		return nil, nil, fmt.Errorf("insecure_tls is disabled on this server")
+	}
+	// Overrides for other hosts would redirect links of the crawled site
+	for host := range access.Resolve {
+		hostname := host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			hostname = name
+		}
+		if !strings.EqualFold(hostname, u.Hostname()) {
+			return nil, nil, fmt.Errorf("resolve is only allowed for %s, not %s", u.Hostname(), host)
+		}
+	}
+
+	crawlAccess := &crawler.Access{
+		Hosts:              []string{u.Hostname()},
+		Username:           access.Username,
+		Password:           access.Password,
+		Headers:            make(http.Header),
+		Resolve:            access.Resolve,
+		Proxy:              access.Proxy,
+		InsecureSkipVerify: access.InsecureTLS,
+	}
+	for name, value := range access.Cookies {
+		crawlAccess.Cookies = append(crawlAccess.Cookies, &http.Cookie{Name: name, Value: value})
+	}
+	for name, value := range access.Headers {
+		crawlAccess.Headers.Set(name, value)
+	}
+	scoped, err := h.crawler.WithAccess(crawlAccess)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scoped, scoped.Close, nil
+}
+
 // HealthCheck handles GET /api/v1/health
 func (h *SEOHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
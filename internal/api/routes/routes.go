@@ -18,6 +18,8 @@ func Setup(seoHandler *handlers.SEOHandler, allowedOrigins []string) http.Handle
 	mux.HandleFunc("POST /api/v1/seo/analyze", seoHandler.AnalyzeURL)
 	mux.HandleFunc("POST /api/v1/seo/keywords/generate", seoHandler.GenerateKeywords)
 	mux.HandleFunc("POST /api/v1/seo/meta/optimize", seoHandler.OptimizeMeta)
+	mux.HandleFunc("POST /api/v1/seo/site/crawl", seoHandler.SiteCrawl)
+	mux.HandleFunc("POST /api/v1/seo/site/graph", seoHandler.SiteGraph)
 
 	// Apply middleware
 	handler := middleware.Logger(mux)
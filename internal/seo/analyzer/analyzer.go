@@ -11,14 +11,21 @@ import (
 
 // SEOScore represents the overall SEO score and breakdown
 type SEOScore struct {
-	Overall      float64            `json:"overall"`
-	Technical    float64            `json:"technical"`
-	Content      float64            `json:"content"`
-	OnPage       float64            `json:"on_page"`
-	Performance  float64            `json:"performance"`
-	Issues       []Issue            `json:"issues"`
-	Opportunities []Opportunity     `json:"opportunities"`
-	Breakdown    map[string]float64 `json:"breakdown"`
+	Overall        float64                    `json:"overall"`
+	Technical      float64                    `json:"technical"`
+	Content        float64                    `json:"content"`
+	OnPage         float64                    `json:"on_page"`
+	Performance    float64                    `json:"performance"`
+	Social         float64                    `json:"social"`
+	Security       float64                    `json:"security"`
+	Indexable      bool                       `json:"indexable"`
+	Indexability   string                     `json:"indexability,omitempty"`
+	NotApplicable  []string                   `json:"not_applicable,omitempty"`
+	StructuredData []StructuredDataValidation `json:"structured_data,omitempty"`
+	LabEstimate    *PerformanceEstimate       `json:"lab_estimate,omitempty"`
+	Issues         []Issue                    `json:"issues"`
+	Opportunities  []Opportunity              `json:"opportunities"`
+	Breakdown      map[string]float64         `json:"breakdown"`
 }
 
 // Issue represents an SEO issue found
@@ -38,7 +45,7 @@ type Opportunity struct {
 	Title       string  `json:"title"`
 	Description string  `json:"description"`
 	Impact      string  `json:"impact"`
-	Effort      string  `json:"effort"` // low, medium, high
+	Effort      string  `json:"effort"`    // low, medium, high
 	Potential   float64 `json:"potential"` // potential score improvement
 }
 
@@ -64,6 +71,21 @@ func (a *Analyzer) Analyze(result *crawler.CrawlResult) *SEOScore {
 
 	// Analyze different aspects
 	score.Technical = a.analyzeTechnical(result, score)
+
+	// Social previews are scored separately and do not affect the overall score
+	score.Social = a.analyzeSocial(result, score)
+
+	// Security headers are scored separately as well
+	score.Security = a.analyzeSecurity(result, score)
+
+	// Content and on-page checks only matter for pages meant to be indexed
+	if isIntentionallyNonIndexable(result) {
+		score.Performance = a.analyzePerformance(result, score)
+		score.NotApplicable = []string{"content", "on_page"}
+		score.Overall = score.Technical*0.625 + score.Performance*0.375
+		return score
+	}
+
 	score.Content = a.analyzeContent(result, score)
 	score.OnPage = a.analyzeOnPage(result, score)
 	score.Performance = a.analyzePerformance(result, score)
@@ -97,7 +119,10 @@ func (a *Analyzer) analyzeTechnical(result *crawler.CrawlResult, score *SEOScore
 	}
 
 	// Status code check
-	if result.StatusCode != 200 {
+	switch {
+	case result.BlockedByRobotsTxt:
+		// Never requested, the indexability checks report the block
+	case result.StatusCode != 200:
 		points -= 20
 		score.Issues = append(score.Issues, Issue{
 			Severity:    "critical",
@@ -107,10 +132,13 @@ func (a *Analyzer) analyzeTechnical(result *crawler.CrawlResult, score *SEOScore
 			Impact:      "Search engines may not index this page",
 			HowToFix:    "Fix server configuration or broken links",
 		})
-	} else {
+	default:
 		score.Breakdown["status_code"] = 20
 	}
 
+	// Error pages that return 200
+	points -= a.analyzeSoft404(result, score)
+
 	// Canonical URL check
 	if result.CanonicalURL == "" {
 		points -= 5
@@ -127,20 +155,17 @@ func (a *Analyzer) analyzeTechnical(result *crawler.CrawlResult, score *SEOScore
 		score.Breakdown["canonical"] = 5
 	}
 
-	// Mobile-friendly check
-	if !result.MobileFriendly {
-		points -= 10
-		score.Issues = append(score.Issues, Issue{
-			Severity:    "high",
-			Category:    "mobile",
-			Title:       "Not Mobile-Friendly",
-			Description: "Missing or incorrect viewport meta tag",
-			Impact:      "Poor mobile experience and ranking penalty",
-			HowToFix:    "Add <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">",
-		})
-	} else {
-		score.Breakdown["mobile"] = 10
-	}
+	// Mobile checks
+	points -= a.analyzeMobile(result, score)
+
+	// Redirect checks
+	points -= a.analyzeRedirects(result, score)
+
+	// Indexability checks
+	points -= a.analyzeIndexability(result, score)
+
+	// Language and hreflang checks
+	points -= a.analyzeInternational(result, score)
 
 	return math.Max(0, points)
 }
@@ -217,6 +242,9 @@ func (a *Analyzer) analyzeContent(result *crawler.CrawlResult, score *SEOScore)
 		score.Breakdown["h2"] = 10
 	}
 
+	// Heading hierarchy
+	points -= a.analyzeHeadings(result, score)
+
 	// Keyword optimization (if keywords provided)
 	if len(a.targetKeywords) > 0 {
 		keywordScore := a.analyzeKeywordUsage(result)
@@ -322,6 +350,15 @@ func (a *Analyzer) analyzeOnPage(result *crawler.CrawlResult, score *SEOScore) f
 		score.Breakdown["image_alt"] = 10
 	}
 
+	// Image size, format, dimensions and file names
+	points -= a.analyzeImages(result, score)
+
+	// Links and anchor texts
+	points -= a.analyzeLinks(result, score)
+
+	// Structured data
+	points -= a.analyzeStructuredData(result, score)
+
 	return math.Max(0, points)
 }
 
@@ -355,6 +392,15 @@ func (a *Analyzer) analyzePerformance(result *crawler.CrawlResult, score *SEOSco
 		score.Breakdown["load_time"] = 30
 	}
 
+	// Server response, compression and size of the page
+	points -= a.analyzeTransfer(result, score)
+
+	// Compression and caching of stylesheets, scripts and images
+	points -= a.analyzeAssets(result, score)
+
+	// Estimated loading metrics, render-blocking resources and page weight
+	points -= a.analyzeLabEstimate(result, score)
+
 	return math.Max(0, points)
 }
 
@@ -368,9 +414,6 @@ func (a *Analyzer) analyzeKeywordUsage(result *crawler.CrawlResult) float64 {
 	maxScore := 100.0
 	pointsPerKeyword := maxScore / float64(len(a.targetKeywords))
 
-	content := strings.ToLower(result.Title + " " + result.MetaDescription + " " +
-		strings.Join(result.H1Tags, " ") + " " + strings.Join(result.H2Tags, " "))
-
 	for _, keyword := range a.targetKeywords {
 		keyword = strings.ToLower(keyword)
 
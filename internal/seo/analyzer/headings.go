@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// Heading outline limits
+const (
+	maxHeadingWords       = 20
+	maxBoilerplateHeading = 3
+)
+
+// analyzeHeadings checks the heading outline for hierarchy problems and
+// returns the points to deduct from the content score
+func (a *Analyzer) analyzeHeadings(result *crawler.CrawlResult, score *SEOScore) float64 {
+	headings := result.Headings
+	if len(headings) == 0 {
+		return 0
+	}
+
+	penalty := 0.0
+
+	// Skipped levels, e.g. H2 followed by H4
+	var skipped []string
+	previous := 0
+	for _, h := range headings {
+		if previous > 0 && h.Level > previous+1 {
+			skipped = append(skipped, fmt.Sprintf("H%d → H%d %q", previous, h.Level, shortHeading(h.Text)))
+		}
+		previous = h.Level
+	}
+	if len(skipped) > 0 {
+		penalty += math.Min(10, float64(len(skipped))*2)
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "content",
+			Title:       "Skipped Heading Levels",
+			Description: fmt.Sprintf("%d heading(s) skip a level: %s", len(skipped), strings.Join(limitStrings(skipped, 5), ", ")),
+			Impact:      "Search engines and screen readers cannot derive a clear document outline",
+			HowToFix:    "Nest headings without gaps (H1 → H2 → H3) and use CSS for visual size",
+		})
+	}
+
+	// The first heading of the content should be the H1
+	for _, h := range headings {
+		if h.Region != "" && h.Region != crawler.RegionMain {
+			continue
+		}
+		if h.Level != 1 && len(result.H1Tags) > 0 {
+			score.Opportunities = append(score.Opportunities, Opportunity{
+				Priority:    "low",
+				Category:    "content",
+				Title:       "Content Starts Below H1 Level",
+				Description: fmt.Sprintf("The first content heading is an H%d (%q) instead of the H1", h.Level, shortHeading(h.Text)),
+				Impact:      "The main topic of the page is less prominent in the outline",
+				Effort:      "low",
+				Potential:   2,
+			})
+		}
+		break
+	}
+
+	// Empty headings
+	empty := 0
+	for _, h := range headings {
+		if h.Text == "" {
+			empty++
+		}
+	}
+	if empty > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "content",
+			Title:       "Empty Headings",
+			Description: fmt.Sprintf("%d heading(s) contain no text or image ALT text", empty),
+			Impact:      "Empty headings break the outline and confuse screen readers",
+			HowToFix:    "Remove empty heading tags or give them descriptive text",
+		})
+	}
+
+	// Headings used for styling: paragraph-length text, sections without
+	// content and site chrome marked up as headings
+	var long, hollow, boilerplate []string
+	for i, h := range headings {
+		if len(strings.Fields(h.Text)) > maxHeadingWords {
+			long = append(long, shortHeading(h.Text))
+		}
+		if i+1 < len(headings) && h.Text != "" {
+			next := headings[i+1]
+			if next.Level <= h.Level && next.Region == h.Region && next.WordOffset == h.WordOffset+len(strings.Fields(h.Text)) && !h.ImageOnly {
+				hollow = append(hollow, fmt.Sprintf("H%d %q", h.Level, shortHeading(h.Text)))
+			}
+		}
+		if h.Region == crawler.RegionNav || h.Region == crawler.RegionFooter || h.Region == crawler.RegionAside {
+			boilerplate = append(boilerplate, fmt.Sprintf("H%d %q", h.Level, shortHeading(h.Text)))
+		}
+	}
+	if len(long) > 0 {
+		penalty += 3
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "content",
+			Title:       "Overlong Headings",
+			Description: fmt.Sprintf("%d heading(s) have more than %d words, e.g. %q", len(long), maxHeadingWords, long[0]),
+			Impact:      "Body text styled as a heading dilutes the outline",
+			Effort:      "low",
+			Potential:   3,
+		})
+	}
+	if len(hollow) > 1 {
+		penalty += 3
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "content",
+			Title:       "Headings Without Content",
+			Description: fmt.Sprintf("%d heading(s) are directly followed by another heading: %s", len(hollow), strings.Join(limitStrings(hollow, 5), ", ")),
+			Impact:      "Headings used for visual styling do not describe a section",
+			Effort:      "low",
+			Potential:   3,
+		})
+	}
+	if len(boilerplate) > maxBoilerplateHeading {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "content",
+			Title:       "Headings in Navigation or Footer",
+			Description: fmt.Sprintf("%d headings are part of navigation, sidebar or footer: %s", len(boilerplate), strings.Join(limitStrings(boilerplate, 5), ", ")),
+			Impact:      "Repeated site chrome headings add noise to the outline of every page",
+			Effort:      "low",
+			Potential:   2,
+		})
+	}
+
+	// H1 outside the main content
+	for _, h := range headings {
+		if h.Level == 1 && (h.Region == crawler.RegionNav || h.Region == crawler.RegionFooter || h.Region == crawler.RegionAside) {
+			penalty += 3
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "low",
+				Category:    "content",
+				Title:       "H1 Outside Main Content",
+				Description: fmt.Sprintf("H1 %q is placed in the %s", shortHeading(h.Text), h.Region),
+				Impact:      "The H1 should describe the page, not the site chrome",
+				HowToFix:    "Move the H1 into the main content and use a styled element for logos or menus",
+			})
+			break
+		}
+	}
+
+	if penalty == 0 {
+		score.Breakdown["heading_outline"] = 10
+	}
+	return math.Min(15, penalty)
+}
+
+// shortHeading truncates a heading text for issue descriptions
+func shortHeading(text string) string {
+	if text == "" {
+		return "(empty)"
+	}
+	runes := []rune(text)
+	if len(runes) > 60 {
+		return string(runes[:57]) + "..."
+	}
+	return text
+}
+
+// limitStrings returns at most n entries, marking omitted ones
+func limitStrings(values []string, n int) []string {
+	if len(values) <= n {
+		return values
+	}
+	return append(values[:n:n], fmt.Sprintf("and %d more", len(values)-n))
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// analyzeInternational checks the language signals of a single page and
+// returns the points to deduct from the technical score. Return links and
+// targets are validated across a site crawl.
+func (a *Analyzer) analyzeInternational(result *crawler.CrawlResult, score *SEOScore) float64 {
+	penalty := 0.0
+
+	if result.Language == "" {
+		penalty += 3
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "international",
+			Title:       "Missing HTML lang Attribute",
+			Description: "The <html> element declares no language",
+			Impact:      "Search engines and screen readers have to guess the page language",
+			Effort:      "low",
+			Potential:   3,
+		})
+	} else if msg := crawler.HreflangCodeError(result.Language); msg != "" {
+		penalty += 3
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "low",
+			Category:    "international",
+			Title:       "Invalid HTML lang Attribute",
+			Description: msg,
+			Impact:      "The declared page language cannot be interpreted",
+			HowToFix:    `Use a language code such as "de" or "de-AT"`,
+		})
+	}
+
+	if len(result.Hreflang) == 0 {
+		return penalty
+	}
+
+	var invalid []string
+	hasSelf, hasXDefault := false, false
+	targets := make(map[string]string)
+	var conflicts []string
+	self := strings.TrimSuffix(result.FinalURL, "/")
+	for _, link := range result.Hreflang {
+		lang := strings.ToLower(link.Lang)
+		if msg := crawler.HreflangCodeError(lang); msg != "" {
+			invalid = append(invalid, msg)
+			continue
+		}
+		if previous, ok := targets[lang]; ok && previous != link.URL {
+			conflicts = append(conflicts, fmt.Sprintf("%s → %s and %s", link.Lang, previous, link.URL))
+		}
+		targets[lang] = link.URL
+		if lang == crawler.HreflangXDefault {
+			hasXDefault = true
+		}
+		if strings.TrimSuffix(link.URL, "/") == self {
+			hasSelf = true
+		}
+	}
+
+	if len(invalid) > 0 {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "international",
+			Title:       "Invalid Hreflang Codes",
+			Description: strings.Join(limitStrings(uniqueStrings(invalid), 5), "; "),
+			Impact:      "Search engines ignore annotations with invalid language or region codes",
+			HowToFix:    "Use ISO 639-1 language codes with optional ISO 3166-1 regions, e.g. de-DE, de-AT, de-CH",
+		})
+	}
+	if len(conflicts) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "international",
+			Title:       "Conflicting Hreflang Targets",
+			Description: strings.Join(limitStrings(conflicts, 5), "; "),
+			Impact:      "Search engines cannot tell which URL serves the language",
+			HowToFix:    "Declare exactly one URL per language and region",
+		})
+	}
+	if !hasSelf {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "international",
+			Title:       "Missing Hreflang Self-Reference",
+			Description: "The hreflang annotations do not include the page itself",
+			Impact:      "Search engines may ignore the annotation set",
+			HowToFix:    "Add an hreflang link for the page's own language pointing to its canonical URL",
+		})
+	}
+	if !hasXDefault {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "international",
+			Title:       "Missing x-default",
+			Description: "No hreflang=\"x-default\" fallback declared",
+			Impact:      "Users with other languages may land on an unsuitable version",
+			Effort:      "low",
+			Potential:   2,
+		})
+	}
+
+	// The page's own annotation should match its declared language
+	if own, ok := ownHreflang(result); ok && result.Language != "" {
+		declared, _, _ := strings.Cut(own, "-")
+		actual, _, _ := strings.Cut(strings.ToLower(result.Language), "-")
+		if declared != actual {
+			penalty += 3
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "low",
+				Category:    "international",
+				Title:       "Hreflang and HTML lang Disagree",
+				Description: fmt.Sprintf("The page is annotated as %q but declares <html lang=%q>", own, result.Language),
+				Impact:      "Mixed language signals weaken geo-targeting",
+				HowToFix:    "Align the hreflang value of the page with its lang attribute",
+			})
+		}
+	}
+
+	if penalty == 0 {
+		score.Breakdown["hreflang"] = 10
+	}
+	return penalty
+}
+
+// ownHreflang returns the hreflang value pointing to the page itself
+func ownHreflang(result *crawler.CrawlResult) (string, bool) {
+	self := strings.TrimSuffix(result.FinalURL, "/")
+	for _, link := range result.Hreflang {
+		lang := strings.ToLower(link.Lang)
+		if lang != crawler.HreflangXDefault && strings.TrimSuffix(link.URL, "/") == self {
+			return lang, true
+		}
+	}
+	return "", false
+}
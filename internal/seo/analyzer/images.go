@@ -0,0 +1,254 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// Image size limits
+const (
+	maxImageBytes       = 200 * 1024
+	minModernImageBytes = 30 * 1024
+	maxResponsiveWidth  = 1000
+	eagerImages         = 3
+)
+
+// cameraFileName matches file names given by cameras, phones and screenshot
+// tools, e.g. IMG_1234, DSC01234, PXL_20240101, Bildschirmfoto 2024-01-01
+var cameraFileName = regexp.MustCompile(`^(img|image|dsc|dscn|dscf|dcim|pxl|photo|foto|bild|pic|picture|screenshot|bildschirmfoto|untitled|unbenannt|whatsapp image)[-_ ]?[\d\-_ .]*$`)
+
+// hashFileName matches numeric, hexadecimal and UUID file names
+var hashFileName = regexp.MustCompile(`^(\d+|[0-9a-f]{16,}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// genericAltTexts are ALT texts that do not describe the image
+var genericAltTexts = map[string]bool{
+	"image":   true,
+	"img":     true,
+	"bild":    true,
+	"foto":    true,
+	"photo":   true,
+	"picture": true,
+	"grafik":  true,
+	"graphic": true,
+}
+
+// analyzeImages checks file size, format, dimensions, file names and ALT
+// texts of the images on a page and returns the points to deduct from the
+// on-page score
+func (a *Analyzer) analyzeImages(result *crawler.CrawlResult, score *SEOScore) float64 {
+	if len(result.Images) == 0 {
+		return 0
+	}
+
+	var oversized, upscaled, legacy, unsized, badNames, badAlts, notResponsive []string
+	seen := make(map[string]bool)
+	for _, img := range result.Images {
+		if seen[img.Src] {
+			continue
+		}
+		seen[img.Src] = true
+		name := imageFileName(img.Src)
+
+		if img.Width == 0 || img.Height == 0 {
+			unsized = append(unsized, img.Src)
+		}
+		if isNonDescriptiveFileName(name) {
+			badNames = append(badNames, img.Src)
+		}
+		if alt := strings.TrimSpace(img.Alt); alt != "" && isFileNameAlt(alt, name) {
+			badAlts = append(badAlts, fmt.Sprintf("%q → %s", alt, img.Src))
+		}
+
+		if !img.Checked || img.StatusCode != http.StatusOK {
+			continue
+		}
+		if img.Size > maxImageBytes {
+			oversized = append(oversized, fmt.Sprintf("%s (%s)", img.Src, formatBytes(img.Size)))
+		}
+		if img.Width > 0 && img.ActualWidth > 2*img.Width && img.Srcset == "" {
+			upscaled = append(upscaled, fmt.Sprintf("%s (%dpx shown at %dpx)", img.Src, img.ActualWidth, img.Width))
+		}
+		if img.Size > minModernImageBytes && isLegacyImageType(img.ContentType) && !img.HasModernSource() {
+			legacy = append(legacy, img.Src)
+		}
+		if img.ActualWidth > maxResponsiveWidth && img.Srcset == "" && !hasSourceSrcset(img) {
+			notResponsive = append(notResponsive, fmt.Sprintf("%s (%dpx)", img.Src, img.ActualWidth))
+		}
+	}
+
+	penalty := 0.0
+
+	if len(oversized) > 0 {
+		penalty += math.Min(10, float64(len(oversized))*2)
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "images",
+			Title:       "Oversized Images",
+			Description: fmt.Sprintf("%d image(s) are larger than %s: %s", len(oversized), formatBytes(maxImageBytes), strings.Join(limitStrings(oversized, 5), ", ")),
+			Impact:      "Large images slow down loading, especially the LCP image on mobile",
+			HowToFix:    "Compress images and resize them to the largest size they are displayed at",
+		})
+	}
+
+	if len(upscaled) > 0 {
+		penalty += math.Min(5, float64(len(upscaled)))
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "images",
+			Title:       "Images Larger Than Displayed",
+			Description: fmt.Sprintf("%d image(s) are more than twice as wide as their declared size: %s", len(upscaled), strings.Join(limitStrings(upscaled, 5), ", ")),
+			Impact:      "Browsers download pixels that are never shown",
+			Effort:      "low",
+			Potential:   5,
+		})
+	}
+
+	if len(unsized) > 0 {
+		penalty += math.Min(5, float64(len(unsized)))
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "images",
+			Title:       "Images Without Dimensions",
+			Description: fmt.Sprintf("%d image(s) have no width and height attributes: %s", len(unsized), strings.Join(limitStrings(unsized, 5), ", ")),
+			Impact:      "The layout shifts when the images load (Cumulative Layout Shift)",
+			HowToFix:    "Add width and height attributes with the intrinsic size; CSS can still scale the image",
+		})
+	}
+
+	if len(legacy) > 0 {
+		penalty += math.Min(5, float64(len(legacy)))
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "images",
+			Title:       "Serve Images in Modern Formats",
+			Description: fmt.Sprintf("%d JPEG, PNG or GIF image(s) have no WebP or AVIF version: %s", len(legacy), strings.Join(limitStrings(legacy, 5), ", ")),
+			Impact:      "WebP and AVIF are typically 25-50% smaller at the same quality",
+			Effort:      "medium",
+			Potential:   5,
+		})
+	}
+
+	if len(notResponsive) > 0 {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "images",
+			Title:       "Missing Responsive Images",
+			Description: fmt.Sprintf("%d large image(s) have no srcset: %s", len(notResponsive), strings.Join(limitStrings(notResponsive, 5), ", ")),
+			Impact:      "Phones download the full desktop image",
+			Effort:      "medium",
+			Potential:   0,
+		})
+	}
+
+	if len(badNames) > 0 {
+		penalty += math.Min(5, float64(len(badNames)))
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "images",
+			Title:       "Non-Descriptive Image File Names",
+			Description: fmt.Sprintf("%d image(s) have generic file names: %s", len(badNames), strings.Join(limitStrings(badNames, 5), ", ")),
+			Impact:      "File names are a relevance signal for image search",
+			Effort:      "low",
+			Potential:   5,
+		})
+	}
+
+	if len(badAlts) > 0 {
+		penalty += math.Min(5, float64(len(badAlts)))
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "images",
+			Title:       "ALT Text Is the File Name",
+			Description: fmt.Sprintf("%d image(s) use the file name or a generic word as ALT text: %s", len(badAlts), strings.Join(limitStrings(badAlts, 5), ", ")),
+			Impact:      "Screen readers and search engines get no description of the image",
+			HowToFix:    "Describe what the image shows in a short sentence",
+		})
+	}
+
+	// Images below the first few are likely offscreen
+	if len(result.Images) > eagerImages {
+		lazy := false
+		for _, img := range result.Images[eagerImages:] {
+			lazy = lazy || img.Lazy
+		}
+		if !lazy {
+			score.Opportunities = append(score.Opportunities, Opportunity{
+				Priority:    "low",
+				Category:    "images",
+				Title:       "Offscreen Images Not Lazy-Loaded",
+				Description: fmt.Sprintf("None of the %d images after the first %d use loading=\"lazy\"", len(result.Images)-eagerImages, eagerImages),
+				Impact:      "Images outside the viewport compete with visible content for bandwidth",
+				Effort:      "low",
+				Potential:   0,
+			})
+		}
+	}
+
+	if penalty == 0 {
+		score.Breakdown["images"] = 10
+	}
+	return math.Min(20, penalty)
+}
+
+// imageFileName returns the lowercase file name of an image URL without
+// extension
+func imageFileName(src string) string {
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	name, err := url.PathUnescape(path.Base(u.Path))
+	if err != nil {
+		name = path.Base(u.Path)
+	}
+	return strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+// isNonDescriptiveFileName reports whether an image file name says nothing
+// about the image
+func isNonDescriptiveFileName(name string) bool {
+	if name == "" || name == "." || name == "/" {
+		return false
+	}
+	return cameraFileName.MatchString(name) || hashFileName.MatchString(name)
+}
+
+// isFileNameAlt reports whether an ALT text is the file name of the image or
+// a generic word
+func isFileNameAlt(alt, name string) bool {
+	alt = strings.ToLower(strings.TrimSpace(alt))
+	if genericAltTexts[alt] {
+		return true
+	}
+	alt = strings.TrimSuffix(alt, path.Ext(alt))
+	normalize := strings.NewReplacer("-", " ", "_", " ", "+", " ")
+	return name != "" && normalize.Replace(alt) == normalize.Replace(name)
+}
+
+// isLegacyImageType reports whether a MIME type is JPEG, PNG or GIF
+func isLegacyImageType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range []string{"image/jpeg", "image/jpg", "image/png", "image/gif"} {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSourceSrcset reports whether a <picture> source offers several sizes
+func hasSourceSrcset(img crawler.Image) bool {
+	for _, source := range img.Sources {
+		if strings.Contains(source.Srcset, ",") || source.Media != "" {
+			return true
+		}
+	}
+	return false
+}
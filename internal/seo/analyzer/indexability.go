@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// analyzeIndexability checks the indexability verdict of a page and returns
+// the points to deduct from the technical score
+func (a *Analyzer) analyzeIndexability(result *crawler.CrawlResult, score *SEOScore) float64 {
+	verdict := result.Indexability
+	score.Indexable = verdict.Indexable
+	score.Indexability = verdict.Reason
+
+	penalty := 0.0
+	directives := result.EffectiveRobotsDirectives("googlebot")
+
+	if verdict.Indexable || verdict.Reason == crawler.IndexabilityRedirected {
+		// Redirects are audited by analyzeRedirects; the final page is indexable
+		score.Breakdown["indexability"] = 10
+	} else if isImportantPage(result) {
+		penalty += 25
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "critical",
+			Category:    "indexability",
+			Title:       "Important Page Not Indexable",
+			Description: fmt.Sprintf("%s is a key page but cannot be indexed: %s", result.URL, verdict.Detail),
+			Impact:      "The page will not appear in search results",
+			HowToFix:    indexabilityFix(verdict.Reason),
+		})
+	} else if verdict.Reason != crawler.IndexabilityNon200 {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "low",
+			Category:    "indexability",
+			Title:       "Page Not Indexable",
+			Description: fmt.Sprintf("Page is excluded from indexing: %s", verdict.Detail),
+			Impact:      "Expected for utility, filter or duplicate pages; verify this is intentional",
+			HowToFix:    indexabilityFix(verdict.Reason),
+		})
+	}
+
+	// noindex combined with a canonical to another URL sends mixed signals
+	if directives.Noindex && result.IsCanonicalized() {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "indexability",
+			Title:       "Conflicting Indexing Signals",
+			Description: fmt.Sprintf("Page is noindex but also canonicalised to %s", result.CanonicalURL),
+			Impact:      "Search engines may pass the noindex on to the canonical target",
+			HowToFix:    "Use either noindex or a canonical to another URL, not both",
+		})
+	}
+
+	if directives.Nofollow && verdict.Indexable {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "indexability",
+			Title:       "Page-Level Nofollow",
+			Description: "Robots directives tell search engines not to follow any link on this page",
+			Impact:      "Internal links on this page pass no signals",
+			HowToFix:    "Remove nofollow from the robots meta tag or X-Robots-Tag header",
+		})
+	}
+
+	return penalty
+}
+
+// isImportantPage reports whether a page is expected to be indexable: the
+// homepage, pages close to it and pages listed in the sitemap
+func isImportantPage(result *crawler.CrawlResult) bool {
+	if u, err := url.Parse(result.URL); err == nil && (u.Path == "" || u.Path == "/") {
+		return true
+	}
+	if result.FoundVia == crawler.FoundViaSitemap {
+		return true
+	}
+	return result.FoundVia != "" && result.Depth <= 1
+}
+
+// isIntentionallyNonIndexable reports whether a page was deliberately kept
+// out of the index, in which case content and on-page checks don't apply
+func isIntentionallyNonIndexable(result *crawler.CrawlResult) bool {
+	if result.Indexability.Indexable || isImportantPage(result) {
+		return false
+	}
+	switch result.Indexability.Reason {
+	case crawler.IndexabilityNoindex, crawler.IndexabilityCanonicalized, crawler.IndexabilityBlockedByRobots:
+		return true
+	}
+	return false
+}
+
+// indexabilityFix returns the fix for a non-indexable reason
+func indexabilityFix(reason string) string {
+	switch reason {
+	case crawler.IndexabilityBlockedByRobots:
+		return "Remove the Disallow rule matching this URL from robots.txt"
+	case crawler.IndexabilityNoindex:
+		return "Remove noindex from the robots meta tag and X-Robots-Tag header"
+	case crawler.IndexabilityCanonicalized:
+		return "Point the canonical tag at the page itself"
+	default:
+		return "Make the page return status 200"
+	}
+}
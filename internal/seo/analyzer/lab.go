@@ -0,0 +1,249 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// Simulated mobile connection, matching the "Slow 4G" throttling of Lighthouse
+const (
+	labRTTMs          = 150
+	labBytesPerMs     = 200 // 1.6 Mbit/s
+	labParallelFetch  = 6
+	labConnectionRTTs = 3 // DNS, TCP and TLS
+)
+
+// Lab estimate thresholds, based on the Core Web Vitals
+const (
+	lcpGoodMs          = 2500
+	lcpPoorMs          = 4000
+	maxPageWeightBytes = 3 * 1024 * 1024
+	maxPageRequests    = 100
+	maxScriptBytes     = 500 * 1024
+)
+
+// PerformanceEstimate is a lab-style estimate of the loading metrics on a
+// throttled mobile connection, derived from the measured response time and
+// the sizes of the subresources instead of a browser run
+type PerformanceEstimate struct {
+	FCPMs          int64 `json:"fcp_ms"`
+	LCPMs          int64 `json:"lcp_ms"`
+	LoadMs         int64 `json:"load_ms"`
+	Requests       int   `json:"requests"`
+	TotalBytes     int64 `json:"total_bytes"`
+	RenderBlocking int   `json:"render_blocking"`
+	// Unmeasured counts resources whose size is unknown and not included
+	Unmeasured int `json:"unmeasured,omitempty"`
+}
+
+// estimatePerformance simulates the page load: the document, then the
+// render-blocking resources up to the first paint, then the first image of
+// the body as the likely LCP element, then the remaining resources
+func estimatePerformance(result *crawler.CrawlResult) *PerformanceEstimate {
+	weight := result.Weight
+	estimate := &PerformanceEstimate{
+		Requests:       weight.Requests,
+		TotalBytes:     weight.TotalBytes,
+		RenderBlocking: weight.RenderBlocking,
+		Unmeasured:     weight.Unmeasured,
+	}
+
+	pageHost := ""
+	if u, err := url.Parse(result.FinalURL); err == nil {
+		pageHost = u.Host
+	}
+	transferMs := func(bytes int64) int64 {
+		return bytes / labBytesPerMs
+	}
+
+	// Document: connection setup, redirects, server response and download
+	document := int64(labConnectionRTTs*labRTTMs) +
+		int64(len(result.RedirectChain)*labRTTMs) +
+		result.Transfer.TTFBMs +
+		transferMs(result.Transfer.TransferSize)
+
+	// Render-blocking resources load in parallel and share the bandwidth
+	var blockingBytes int64
+	newOrigin := false
+	var lcpImage *crawler.Asset
+	for i := range result.Assets {
+		asset := &result.Assets[i]
+		if asset.RenderBlocking {
+			blockingBytes += asset.Transfer.TransferSize
+			if u, err := url.Parse(asset.URL); err == nil && u.Host != pageHost {
+				newOrigin = true
+			}
+		}
+		if asset.Kind == crawler.AssetImage && !asset.InHead && lcpImage == nil {
+			lcpImage = asset
+		}
+	}
+	estimate.FCPMs = document
+	if weight.RenderBlocking > 0 {
+		estimate.FCPMs += labRTTMs + transferMs(blockingBytes)
+		if newOrigin {
+			estimate.FCPMs += labConnectionRTTs * labRTTMs
+		}
+	}
+
+	estimate.LCPMs = estimate.FCPMs
+	if lcpImage != nil {
+		estimate.LCPMs += labRTTMs + transferMs(lcpImage.Transfer.TransferSize)
+		if lcpImage.Lazy {
+			// Lazy images are only requested after layout
+			estimate.LCPMs += labRTTMs
+		}
+	}
+
+	assetRequests := weight.Requests - 1 - len(result.RedirectChain)
+	rounds := int64(math.Ceil(float64(assetRequests) / labParallelFetch))
+	estimate.LoadMs = document + rounds*labRTTMs + transferMs(weight.TotalBytes-result.Transfer.TransferSize)
+	if estimate.LoadMs < estimate.LCPMs {
+		estimate.LoadMs = estimate.LCPMs
+	}
+	return estimate
+}
+
+// analyzeLabEstimate scores the estimated loading metrics, render-blocking
+// resources and page weight and returns the points to deduct from the
+// performance score
+func (a *Analyzer) analyzeLabEstimate(result *crawler.CrawlResult, score *SEOScore) float64 {
+	estimate := estimatePerformance(result)
+	score.LabEstimate = estimate
+	penalty := 0.0
+
+	note := ""
+	if estimate.Unmeasured > 0 {
+		note = fmt.Sprintf(" (%d resource(s) of unknown size not included)", estimate.Unmeasured)
+	}
+
+	switch {
+	case estimate.LCPMs > lcpPoorMs:
+		penalty += 15
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "performance",
+			Title:       "Slow Largest Contentful Paint (Estimated)",
+			Description: fmt.Sprintf("Estimated LCP on a mobile connection is %.1fs (target: <%.1fs)%s", float64(estimate.LCPMs)/1000, float64(lcpGoodMs)/1000, note),
+			Impact:      "LCP is a Core Web Vital and a ranking signal",
+			HowToFix:    "Reduce render-blocking resources, compress the main image and speed up the server response",
+		})
+	case estimate.LCPMs > lcpGoodMs:
+		penalty += 8
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "performance",
+			Title:       "Improve Largest Contentful Paint (Estimated)",
+			Description: fmt.Sprintf("Estimated LCP on a mobile connection is %.1fs (target: <%.1fs)%s", float64(estimate.LCPMs)/1000, float64(lcpGoodMs)/1000, note),
+			Impact:      "LCP is a Core Web Vital and a ranking signal",
+			Effort:      "medium",
+			Potential:   8,
+		})
+	default:
+		score.Breakdown["lcp"] = 15
+	}
+
+	// Render-blocking resources in <head>
+	var blocking []string
+	for _, asset := range result.Assets {
+		if asset.RenderBlocking {
+			blocking = append(blocking, fmt.Sprintf("%s (%s)", asset.URL, asset.Kind))
+		}
+	}
+	if len(blocking) > 0 {
+		penalty += math.Min(10, float64(len(blocking))*2)
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "high",
+			Category:    "performance",
+			Title:       "Eliminate Render-Blocking Resources",
+			Description: fmt.Sprintf("%d resource(s) in <head> block the first paint: %s", len(blocking), strings.Join(limitStrings(blocking, 5), ", ")),
+			Impact:      "The page stays blank until these files are downloaded and processed",
+			Effort:      "medium",
+			Potential:   math.Min(10, float64(len(blocking))*2),
+		})
+	} else {
+		score.Breakdown["render_blocking"] = 10
+	}
+
+	// Lazy loading the likely LCP image delays it
+	for _, asset := range result.Assets {
+		if asset.Kind == crawler.AssetImage && !asset.InHead {
+			if asset.Lazy {
+				score.Opportunities = append(score.Opportunities, Opportunity{
+					Priority:    "medium",
+					Category:    "performance",
+					Title:       "First Image Is Lazy-Loaded",
+					Description: fmt.Sprintf("%s has loading=\"lazy\" although it is likely above the fold", asset.URL),
+					Impact:      "Lazy loading delays the largest contentful paint",
+					Effort:      "low",
+					Potential:   0,
+				})
+			}
+			break
+		}
+	}
+
+	// Page weight
+	if result.Weight.TotalBytes > maxPageWeightBytes {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "performance",
+			Title:       "Heavy Page",
+			Description: fmt.Sprintf("The page transfers %s in %d requests (%s)", formatBytes(result.Weight.TotalBytes), result.Weight.Requests, weightByKind(result.Weight)),
+			Impact:      "Large downloads are slow and expensive on mobile connections",
+			HowToFix:    "Compress images, serve modern formats such as WebP or AVIF and remove unused scripts",
+		})
+	} else {
+		score.Breakdown["page_weight"] = 10
+	}
+
+	if result.Weight.Requests > maxPageRequests {
+		penalty += 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "performance",
+			Title:       "Many Requests",
+			Description: fmt.Sprintf("The page makes %d requests (recommended: at most %d)", result.Weight.Requests, maxPageRequests),
+			Impact:      "Every request adds overhead, especially over HTTP/1.1",
+			Effort:      "medium",
+			Potential:   5,
+		})
+	}
+
+	if scripts := result.Weight.BytesByKind[crawler.AssetScript]; scripts > maxScriptBytes {
+		penalty += 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "performance",
+			Title:       "Heavy JavaScript",
+			Description: fmt.Sprintf("Scripts transfer %s (recommended: under %s)", formatBytes(scripts), formatBytes(maxScriptBytes)),
+			Impact:      "Downloading, parsing and executing JavaScript blocks the main thread and delays interactivity",
+			Effort:      "high",
+			Potential:   5,
+		})
+	}
+
+	return penalty
+}
+
+// weightByKind formats the transferred bytes per resource kind, largest first
+func weightByKind(weight crawler.PageWeight) string {
+	kinds := make([]string, 0, len(weight.BytesByKind))
+	for kind := range weight.BytesByKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return weight.BytesByKind[kinds[i]] > weight.BytesByKind[kinds[j]]
+	})
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s: %s", kind, formatBytes(weight.BytesByKind[kind])))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// maxOutboundLinks is the number of followed external links above which a
+// page looks like a link farm
+const maxOutboundLinks = 100
+
+// genericAnchors are anchor texts that say nothing about the link target
+var genericAnchors = map[string]bool{
+	// German
+	"hier":               true,
+	"hier klicken":       true,
+	"klicken sie hier":   true,
+	"hier entlang":       true,
+	"mehr":               true,
+	"mehr erfahren":      true,
+	"mehr lesen":         true,
+	"mehr infos":         true,
+	"mehr informationen": true,
+	"weiter":             true,
+	"weiterlesen":        true,
+	"zum artikel":        true,
+	"link":               true,
+	"details":            true,
+	// English
+	"here":       true,
+	"click here": true,
+	"more":       true,
+	"read more":  true,
+	"learn more": true,
+	"more info":  true,
+	"continue":   true,
+	"this":       true,
+}
+
+// analyzeLinks audits anchor texts and rel attributes of the links on a page
+// and returns the points to deduct from the on-page score
+func (a *Analyzer) analyzeLinks(result *crawler.CrawlResult, score *SEOScore) float64 {
+	var empty, generic, internalNofollow []string
+	outbound := 0
+	for _, link := range result.LinkDetails {
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		anchor := normalizeAnchor(link.Anchor)
+		switch {
+		case anchor == "" && link.Title == "":
+			empty = append(empty, link.URL)
+		case genericAnchors[anchor]:
+			generic = append(generic, fmt.Sprintf("%q → %s", link.Anchor, link.URL))
+		}
+
+		if link.Internal && link.Nofollow {
+			internalNofollow = append(internalNofollow, link.URL)
+		}
+		if !link.Internal && link.IsFollowed() {
+			outbound++
+		}
+	}
+
+	penalty := 0.0
+
+	if len(empty) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "links",
+			Title:       "Links Without Anchor Text",
+			Description: fmt.Sprintf("%d link(s) have no text, ALT text or label: %s", len(empty), strings.Join(limitStrings(uniqueStrings(empty), 5), ", ")),
+			Impact:      "Search engines get no context about the target and screen readers announce only the URL",
+			HowToFix:    "Add descriptive link text, or ALT text to linked images",
+		})
+	}
+
+	if len(generic) > 0 {
+		penalty += math.Min(5, float64(len(generic)))
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "links",
+			Title:       "Generic Anchor Texts",
+			Description: fmt.Sprintf("%d link(s) use generic anchors: %s", len(generic), strings.Join(limitStrings(generic, 5), ", ")),
+			Impact:      "Descriptive anchors pass topical relevance to the linked page",
+			Effort:      "low",
+			Potential:   5,
+		})
+	}
+
+	if len(internalNofollow) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "links",
+			Title:       "Internal Nofollow Links",
+			Description: fmt.Sprintf("%d internal link(s) are marked nofollow: %s", len(internalNofollow), strings.Join(limitStrings(uniqueStrings(internalNofollow), 5), ", ")),
+			Impact:      "Link equity is not passed within the site and target pages may be crawled less",
+			HowToFix:    "Remove rel=\"nofollow\" from internal links; use noindex or robots.txt to exclude pages",
+		})
+	}
+
+	if outbound > maxOutboundLinks {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "links",
+			Title:       "Excessive Outbound Links",
+			Description: fmt.Sprintf("%d followed external links (recommended: at most %d)", outbound, maxOutboundLinks),
+			Impact:      "Many followed outbound links can make a page look like a link farm",
+			HowToFix:    "Remove unnecessary links and mark paid or user-generated links as sponsored or ugc",
+		})
+	}
+
+	if penalty == 0 && len(result.LinkDetails) > 0 {
+		score.Breakdown["links"] = 10
+	}
+	return penalty
+}
+
+// normalizeAnchor lowercases an anchor text and strips decoration such as arrows
+func normalizeAnchor(anchor string) string {
+	anchor = strings.ToLower(strings.Join(strings.Fields(anchor), " "))
+	return strings.Trim(anchor, " .:…»«›‹>→-")
+}
+
+// uniqueStrings removes duplicates while keeping the order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// analyzeMobile checks the viewport, inline styles and, if it was crawled,
+// the mobile version of a page and returns the points to deduct from the
+// technical score
+func (a *Analyzer) analyzeMobile(result *crawler.CrawlResult, score *SEOScore) float64 {
+	penalty := 0.0
+	viewport := result.Viewport
+
+	switch {
+	case !viewport.Declared():
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "mobile",
+			Title:       "Missing Viewport Meta Tag",
+			Description: "The page declares no viewport and is rendered at desktop width on phones",
+			Impact:      "Poor mobile experience and ranking penalty under mobile-first indexing",
+			HowToFix:    "Add <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">",
+		})
+	case !viewport.AdaptsToDevice():
+		penalty += 10
+		description := fmt.Sprintf("The viewport %q does not follow the device width", viewport.Content)
+		if width := viewport.FixedWidth(); width > 0 {
+			description = fmt.Sprintf("The viewport is fixed to %dpx", width)
+		}
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "mobile",
+			Title:       "Fixed-Width Viewport",
+			Description: description,
+			Impact:      "Phones scale the page down, text becomes tiny and tap targets too small",
+			HowToFix:    "Use width=device-width instead of a fixed pixel width",
+		})
+	}
+
+	if viewport.ZoomDisabled() {
+		penalty += 3
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "mobile",
+			Title:       "Zoom Disabled",
+			Description: fmt.Sprintf("The viewport %q prevents users from zooming", viewport.Content),
+			Impact:      "Users with low vision cannot enlarge the text (WCAG 1.4.4)",
+			HowToFix:    "Remove user-scalable=no and maximum-scale from the viewport",
+		})
+	}
+
+	if len(result.FixedWidths) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "mobile",
+			Title:       "Fixed-Width Elements",
+			Description: fmt.Sprintf("%d element(s) are wider than a phone screen: %s", len(result.FixedWidths), strings.Join(limitStrings(styleFindings(result.FixedWidths), 5), ", ")),
+			Impact:      "Content overflows horizontally and forces users to scroll sideways",
+			HowToFix:    "Use relative widths or max-width instead of fixed pixel widths",
+		})
+	}
+
+	if len(result.SmallFonts) > 0 {
+		penalty += math.Min(5, float64(len(result.SmallFonts)))
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "mobile",
+			Title:       "Small Font Sizes",
+			Description: fmt.Sprintf("%d inline style(s) set fonts below 12px: %s", len(result.SmallFonts), strings.Join(limitStrings(styleFindings(result.SmallFonts), 5), ", ")),
+			Impact:      "Text is hard to read on phones without zooming",
+			Effort:      "low",
+			Potential:   5,
+		})
+	}
+
+	if parity := result.MobileParity; parity != nil {
+		switch {
+		case parity.Error != "":
+			penalty += 10
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "high",
+				Category:    "mobile",
+				Title:       "Mobile Version Not Reachable",
+				Description: fmt.Sprintf("The request with a smartphone user agent failed: %s", parity.Error),
+				Impact:      "Google indexes the mobile version; if it fails, the page may drop out of the index",
+				HowToFix:    "Serve the page to smartphone user agents the same way as to desktop browsers",
+			})
+		case len(parity.Differences) > 0:
+			penalty += 10
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "high",
+				Category:    "mobile",
+				Title:       "Mobile Content Differs From Desktop",
+				Description: strings.Join(limitStrings(parity.Differences, 5), "; "),
+				Impact:      "Only the mobile version is indexed, content missing there does not rank",
+				HowToFix:    "Serve the same content, links, metadata and structured data on mobile and desktop",
+			})
+		}
+	}
+
+	if penalty == 0 {
+		score.Breakdown["mobile"] = 10
+	}
+	return penalty
+}
+
+// styleFindings formats inline style findings for issue descriptions
+func styleFindings(findings []crawler.StyleFinding) []string {
+	formatted := make([]string, 0, len(findings))
+	for _, f := range findings {
+		formatted = append(formatted, fmt.Sprintf("<%s> %s", f.Element, f.Declaration))
+	}
+	return uniqueStrings(formatted)
+}
@@ -0,0 +1,228 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// Transfer thresholds
+const (
+	ttfbGoodMs           = 800
+	ttfbPoorMs           = 1800
+	maxHTMLBytes         = 500 * 1024
+	minCompressibleBytes = 1024
+	minAssetCacheSeconds = 7 * 24 * 3600
+)
+
+// analyzeTransfer checks the server response time, compression, size and
+// protocol of the page and returns the points to deduct from the
+// performance score
+func (a *Analyzer) analyzeTransfer(result *crawler.CrawlResult, score *SEOScore) float64 {
+	t := result.Transfer
+	penalty := 0.0
+
+	// Time to first byte
+	switch {
+	case t.TTFBMs > ttfbPoorMs:
+		penalty += 15
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "performance",
+			Title:       "Slow Server Response",
+			Description: fmt.Sprintf("Time to first byte is %dms (target: <%dms)", t.TTFBMs, ttfbGoodMs),
+			Impact:      "Every other loading step waits for the server, and Googlebot crawls slow sites less",
+			HowToFix:    "Enable server-side page caching, optimize database queries or use a CDN",
+		})
+	case t.TTFBMs > ttfbGoodMs:
+		penalty += 8
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "performance",
+			Title:       "Improve Server Response Time",
+			Description: fmt.Sprintf("Time to first byte is %dms (target: <%dms)", t.TTFBMs, ttfbGoodMs),
+			Impact:      "A faster response improves LCP and crawl budget",
+			Effort:      "medium",
+			Potential:   8,
+		})
+	default:
+		score.Breakdown["ttfb"] = 15
+	}
+
+	// Compression
+	if t.ContentEncoding == "" && t.DecodedSize > minCompressibleBytes {
+		penalty += 15
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "performance",
+			Title:       "HTML Not Compressed",
+			Description: fmt.Sprintf("The page is delivered uncompressed (%s)", formatBytes(t.DecodedSize)),
+			Impact:      "Compression typically reduces HTML transfer size by 70-80%",
+			HowToFix:    "Enable gzip or Brotli compression for text/html on the web server",
+		})
+	} else {
+		score.Breakdown["compression"] = 15
+	}
+
+	// HTML size
+	if t.DecodedSize > maxHTMLBytes {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "performance",
+			Title:       "Oversized HTML",
+			Description: fmt.Sprintf("The HTML document is %s (recommended: under %s)", formatBytes(t.DecodedSize), formatBytes(maxHTMLBytes)),
+			Impact:      "Large documents take longer to download and parse, especially on mobile",
+			HowToFix:    "Remove inlined data, large inline scripts and styles, and paginate long lists",
+		})
+	} else {
+		score.Breakdown["html_size"] = 10
+	}
+
+	// Protocol, only HTTPS connections can negotiate HTTP/2
+	if result.HasHTTPS && strings.HasPrefix(t.Protocol, "HTTP/1") {
+		penalty += 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "performance",
+			Title:       "HTTP/2 Not Used",
+			Description: fmt.Sprintf("The page is served over %s", t.Protocol),
+			Impact:      "HTTP/2 loads many assets in parallel over a single connection",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else if t.Protocol != "" {
+		score.Breakdown["protocol"] = 5
+	}
+
+	// Validators allow cheap conditional requests
+	if t.ETag == "" && t.LastModified == "" && t.DecodedSize > 0 {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "performance",
+			Title:       "No ETag or Last-Modified",
+			Description: "The page sends no validator for conditional requests",
+			Impact:      "Browsers and crawlers have to download the page again even if it did not change",
+			Effort:      "low",
+			Potential:   0,
+		})
+	}
+
+	return penalty
+}
+
+// analyzeAssets checks compression and caching of the stylesheets, scripts
+// and images of the page and returns the points to deduct from the
+// performance score
+func (a *Analyzer) analyzeAssets(result *crawler.CrawlResult, score *SEOScore) float64 {
+	var uncompressed, uncached []string
+	checked := 0
+	for _, asset := range result.Assets {
+		// Iframes are documents, not static assets
+		if asset.StatusCode != http.StatusOK || asset.Kind == crawler.AssetIframe {
+			continue
+		}
+		checked++
+		t := asset.Transfer
+
+		if t.ContentEncoding == "" && t.TransferSize > minCompressibleBytes && isTextContent(t.ContentType) {
+			uncompressed = append(uncompressed, fmt.Sprintf("%s (%s)", asset.URL, formatBytes(t.TransferSize)))
+		}
+
+		lifetime, ok := cacheLifetime(t)
+		switch {
+		case !ok:
+			uncached = append(uncached, asset.URL+" (no cache headers)")
+		case lifetime < minAssetCacheSeconds:
+			uncached = append(uncached, fmt.Sprintf("%s (cached for %s)", asset.URL, time.Duration(lifetime)*time.Second))
+		}
+	}
+	if checked == 0 {
+		return 0
+	}
+
+	penalty := 0.0
+	if len(uncompressed) > 0 {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "performance",
+			Title:       "Uncompressed Text Assets",
+			Description: fmt.Sprintf("%d text asset(s) are delivered uncompressed: %s", len(uncompressed), strings.Join(limitStrings(uncompressed, 5), ", ")),
+			Impact:      "Uncompressed CSS and JavaScript waste bandwidth and delay rendering",
+			HowToFix:    "Enable gzip or Brotli compression for CSS, JavaScript, SVG and JSON",
+		})
+	} else {
+		score.Breakdown["asset_compression"] = 10
+	}
+
+	if len(uncached) > 0 {
+		penalty += math.Min(10, float64(len(uncached)))
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "performance",
+			Title:       "Static Assets Without Long-Term Caching",
+			Description: fmt.Sprintf("%d asset(s) are cached for less than 7 days: %s", len(uncached), strings.Join(limitStrings(uncached, 5), ", ")),
+			Impact:      "Returning visitors download the same files again",
+			HowToFix:    "Serve versioned static files with Cache-Control: public, max-age=31536000, immutable",
+		})
+	} else {
+		score.Breakdown["asset_caching"] = 10
+	}
+
+	return penalty
+}
+
+// cacheLifetime returns how long a response may be cached in seconds and
+// whether any caching header was sent
+func cacheLifetime(t crawler.Transfer) (int64, bool) {
+	if t.CacheControl != "" {
+		for _, directive := range strings.Split(strings.ToLower(t.CacheControl), ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch name {
+			case "no-store", "no-cache":
+				return 0, true
+			case "max-age":
+				if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil {
+					return seconds, true
+				}
+			}
+		}
+	}
+	if t.Expires != "" {
+		expires, err := http.ParseTime(t.Expires)
+		if err != nil {
+			// An invalid date means already expired
+			return 0, true
+		}
+		return int64(math.Max(0, time.Until(expires).Seconds())), true
+	}
+	return 0, t.CacheControl != ""
+}
+
+// isTextContent reports whether a content type benefits from compression
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, text := range []string{"text/", "javascript", "json", "xml", "svg"} {
+		if strings.Contains(contentType, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes formats a byte count for issue descriptions
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.0f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
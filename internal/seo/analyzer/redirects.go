@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// analyzeRedirects audits the redirect chain of a page and returns the
+// points to deduct from the technical score
+func (a *Analyzer) analyzeRedirects(result *crawler.CrawlResult, score *SEOScore) float64 {
+	chain := result.RedirectChain
+	if len(chain) == 0 {
+		score.Breakdown["redirects"] = 10
+		return 0
+	}
+
+	// Redirect loop
+	if result.HasRedirectLoop() {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "critical",
+			Category:    "redirects",
+			Title:       "Redirect Loop",
+			Description: fmt.Sprintf("The URL redirects in a circle: %s", formatRedirectPath(chain)),
+			Impact:      "Page can never be reached by users or search engines",
+			HowToFix:    "Fix the redirect rules so the chain ends at a page returning 200",
+		})
+		return 20
+	}
+
+	penalty := 0.0
+
+	// Redirect to an error page
+	if result.StatusCode >= 400 {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "redirects",
+			Title:       fmt.Sprintf("Redirect to Error Page (%d)", result.StatusCode),
+			Description: fmt.Sprintf("The redirect chain ends at %s with status %d", result.FinalURL, result.StatusCode),
+			Impact:      "Link equity of the redirected URL is lost and users land on an error",
+			HowToFix:    "Redirect to a relevant live page or return 410 if the content is gone",
+		})
+	}
+
+	// Chains longer than one hop
+	if len(chain) > 1 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "redirects",
+			Title:       "Redirect Chain",
+			Description: fmt.Sprintf("%d redirects before reaching the final URL: %s", len(chain), formatRedirectPath(chain)),
+			Impact:      "Wastes crawl budget, slows down users and may lose link equity",
+			HowToFix:    "Redirect directly to the final URL in a single hop and update internal links",
+		})
+	}
+
+	// Temporary redirects
+	var temporary []string
+	for _, hop := range chain {
+		if hop.StatusCode == 302 || hop.StatusCode == 303 || hop.StatusCode == 307 {
+			temporary = append(temporary, fmt.Sprintf("%s (%d)", hop.URL, hop.StatusCode))
+		}
+	}
+	if len(temporary) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "redirects",
+			Title:       "Temporary Redirect",
+			Description: fmt.Sprintf("Temporary redirects found: %s", strings.Join(temporary, ", ")),
+			Impact:      "Search engines may keep the old URL indexed and not pass signals to the target",
+			HowToFix:    "Use 301 (or 308) for permanent moves",
+		})
+	}
+
+	// HTTP -> HTTPS -> www normalized in several hops or back and forth
+	if isOriginPingPong(chain) {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "redirects",
+			Title:       "HTTPS/WWW Redirect Ping-Pong",
+			Description: fmt.Sprintf("Protocol and host are normalized in separate or conflicting hops: %s", formatRedirectPath(chain)),
+			Impact:      "Every request pays for extra round trips and signals are split across hosts",
+			HowToFix:    "Redirect every HTTP and non-canonical host variant directly to https:// on the canonical host",
+		})
+	}
+
+	return penalty
+}
+
+// isOriginPingPong reports whether the scheme/host of a redirect chain
+// changes in more than one hop or returns to an origin it already left
+func isOriginPingPong(chain []crawler.RedirectHop) bool {
+	origins := []string{redirectOrigin(chain[0].URL)}
+	for _, hop := range chain {
+		origins = append(origins, redirectOrigin(hop.Location))
+	}
+
+	changes := 0
+	left := make(map[string]bool)
+	for i := 1; i < len(origins); i++ {
+		if origins[i] == origins[i-1] {
+			continue
+		}
+		changes++
+		left[origins[i-1]] = true
+		if left[origins[i]] {
+			return true
+		}
+	}
+	return changes > 1
+}
+
+// redirectOrigin returns the lowercase scheme://host of a URL
+func redirectOrigin(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return urlStr
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// formatRedirectPath renders a redirect chain as "A (301) → B (302) → C"
+func formatRedirectPath(chain []crawler.RedirectHop) string {
+	parts := make([]string, 0, len(chain)+1)
+	for _, hop := range chain {
+		parts = append(parts, fmt.Sprintf("%s (%d)", hop.URL, hop.StatusCode))
+	}
+	parts = append(parts, chain[len(chain)-1].Location)
+	return strings.Join(parts, " → ")
+}
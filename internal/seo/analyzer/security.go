@@ -0,0 +1,443 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// HSTS and certificate thresholds
+const (
+	hstsMinMaxAge          = 15768000 // six months
+	hstsPreloadMaxAge      = 31536000 // one year, required by hstspreload.org
+	certExpiryWarningDays  = 30
+	certExpiryCriticalDays = 14
+)
+
+// hsts is a parsed Strict-Transport-Security header
+type hsts struct {
+	maxAge            int64
+	includeSubDomains bool
+	preload           bool
+}
+
+// analyzeSecurity checks the security headers, cookies, mixed content and
+// TLS certificate of a page and returns the security score
+func (a *Analyzer) analyzeSecurity(result *crawler.CrawlResult, score *SEOScore) float64 {
+	points := 100.0
+	headers := result.Headers
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	if result.HasHTTPS {
+		points -= a.analyzeHSTS(headers, score)
+		points -= a.analyzeTLS(result, score)
+		points -= a.analyzeMixedContent(result, score)
+	} else {
+		// HSTS, TLS and mixed content require HTTPS, which is reported as a
+		// technical issue
+		points -= 55
+	}
+
+	// Content-Security-Policy
+	if policies := headers.Values("Content-Security-Policy"); len(policies) == 0 {
+		deduction := 15.0
+		description := "No Content-Security-Policy header found"
+		if headers.Get("Content-Security-Policy-Report-Only") != "" {
+			deduction = 10
+			description = "The Content-Security-Policy is only sent in report-only mode and not enforced"
+		}
+		points -= deduction
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Missing Content-Security-Policy",
+			Description: description,
+			Impact:      "Injected scripts (XSS) can run without restriction",
+			HowToFix:    "Send a Content-Security-Policy header that limits script sources, e.g. script-src 'self'",
+		})
+	} else if weaknesses := cspWeaknesses(policies); len(weaknesses) > 0 {
+		points -= 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Weak Content-Security-Policy",
+			Description: strings.Join(weaknesses, "; "),
+			Impact:      "The policy does not prevent injected scripts from running",
+			HowToFix:    "Use nonces or hashes instead of 'unsafe-inline' and avoid 'unsafe-eval' and wildcard sources",
+		})
+	} else {
+		score.Breakdown["csp"] = 15
+	}
+
+	// X-Content-Type-Options
+	if !strings.EqualFold(strings.TrimSpace(headers.Get("X-Content-Type-Options")), "nosniff") {
+		points -= 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Missing X-Content-Type-Options",
+			Description: "The X-Content-Type-Options: nosniff header is not set",
+			Impact:      "Browsers may interpret uploads or responses as scripts (MIME sniffing)",
+			HowToFix:    "Send X-Content-Type-Options: nosniff with every response",
+		})
+	} else {
+		score.Breakdown["x_content_type_options"] = 10
+	}
+
+	// Referrer-Policy
+	referrerPolicy := strings.ToLower(strings.TrimSpace(headers.Get("Referrer-Policy")))
+	switch {
+	case referrerPolicy == "":
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "security",
+			Title:       "Missing Referrer-Policy",
+			Description: "No Referrer-Policy header found, the browser default applies",
+			Impact:      "An explicit policy keeps URLs with sensitive parameters from leaking to other sites",
+			Effort:      "low",
+			Potential:   5,
+		})
+	case strings.Contains(referrerPolicy, "unsafe-url"), strings.Contains(referrerPolicy, "no-referrer-when-downgrade"):
+		points -= 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Permissive Referrer-Policy",
+			Description: fmt.Sprintf("Referrer-Policy %q sends the full URL to other sites", referrerPolicy),
+			Impact:      "Paths and query parameters leak to third parties",
+			HowToFix:    "Use Referrer-Policy: strict-origin-when-cross-origin",
+		})
+	default:
+		score.Breakdown["referrer_policy"] = 5
+	}
+
+	// Permissions-Policy
+	if headers.Get("Permissions-Policy") == "" {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "security",
+			Title:       "Missing Permissions-Policy",
+			Description: "No Permissions-Policy header found",
+			Impact:      "Embedded third-party content can request camera, microphone or location access",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else {
+		score.Breakdown["permissions_policy"] = 5
+	}
+
+	points -= a.analyzeCookies(result, score)
+
+	return math.Max(0, points)
+}
+
+// analyzeHSTS checks the Strict-Transport-Security header and returns the
+// points to deduct
+func (a *Analyzer) analyzeHSTS(headers http.Header, score *SEOScore) float64 {
+	value := headers.Get("Strict-Transport-Security")
+	if value == "" {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "security",
+			Title:       "Missing HSTS Header",
+			Description: "No Strict-Transport-Security header found",
+			Impact:      "The first request of a visit can be downgraded to HTTP and intercepted",
+			HowToFix:    "Send Strict-Transport-Security: max-age=31536000; includeSubDomains",
+		})
+		return 15
+	}
+
+	policy := parseHSTS(value)
+	if policy.maxAge < hstsMinMaxAge {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Short HSTS max-age",
+			Description: fmt.Sprintf("max-age is %d seconds (recommended: at least %d)", policy.maxAge, hstsMinMaxAge),
+			Impact:      "Browsers forget the HTTPS requirement quickly",
+			HowToFix:    "Increase max-age to at least one year (31536000)",
+		})
+		return 10
+	}
+
+	// Preload eligibility as required by hstspreload.org
+	var missing []string
+	if policy.maxAge < hstsPreloadMaxAge {
+		missing = append(missing, fmt.Sprintf("max-age of at least %d", hstsPreloadMaxAge))
+	}
+	if !policy.includeSubDomains {
+		missing = append(missing, "includeSubDomains")
+	}
+	if !policy.preload {
+		missing = append(missing, "the preload directive")
+	}
+	if len(missing) > 0 {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "security",
+			Title:       "HSTS Not Preload-Ready",
+			Description: "The HSTS header lacks " + strings.Join(missing, ", "),
+			Impact:      "Preloaded domains are never requested over HTTP, not even on the first visit",
+			Effort:      "medium",
+			Potential:   0,
+		})
+	}
+
+	score.Breakdown["hsts"] = 15
+	return 0
+}
+
+// parseHSTS parses a Strict-Transport-Security header value
+func parseHSTS(value string) hsts {
+	var policy hsts
+	for _, directive := range strings.Split(value, ";") {
+		name, val, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			policy.maxAge, _ = strconv.ParseInt(strings.Trim(strings.TrimSpace(val), `"`), 10, 64)
+		case "includesubdomains":
+			policy.includeSubDomains = true
+		case "preload":
+			policy.preload = true
+		}
+	}
+	return policy
+}
+
+// cspWeaknesses returns the weaknesses of the script sources of the given
+// policies. All policies are enforced, so a weakness only matters if every
+// policy has it.
+func cspWeaknesses(policies []string) []string {
+	var common []string
+	for i, policy := range policies {
+		weaknesses := cspPolicyWeaknesses(policy)
+		if i == 0 {
+			common = weaknesses
+			continue
+		}
+		var both []string
+		for _, w := range common {
+			for _, other := range weaknesses {
+				if w == other {
+					both = append(both, w)
+					break
+				}
+			}
+		}
+		common = both
+	}
+	return common
+}
+
+// cspPolicyWeaknesses returns the weaknesses of the script sources of a
+// single policy
+func cspPolicyWeaknesses(policy string) []string {
+	directives := make(map[string][]string)
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(strings.ToLower(directive))
+		if len(fields) > 0 {
+			directives[fields[0]] = fields[1:]
+		}
+	}
+
+	sources, ok := directives["script-src"]
+	if !ok {
+		sources, ok = directives["default-src"]
+	}
+	if !ok {
+		return []string{"the policy does not restrict script sources (no script-src or default-src)"}
+	}
+
+	var weaknesses []string
+	inline, eval, wildcard, strict := false, false, "", false
+	for _, source := range sources {
+		switch {
+		case source == "'unsafe-inline'":
+			inline = true
+		case source == "'unsafe-eval'":
+			eval = true
+		case source == "*", source == "http:", source == "https:", source == "data:":
+			wildcard = source
+		case strings.HasPrefix(source, "'nonce-"), strings.HasPrefix(source, "'sha"), source == "'strict-dynamic'":
+			// Browsers ignore 'unsafe-inline' if a nonce or hash is present
+			strict = true
+		}
+	}
+	if inline && !strict {
+		weaknesses = append(weaknesses, "'unsafe-inline' allows inline scripts")
+	}
+	if eval {
+		weaknesses = append(weaknesses, "'unsafe-eval' allows eval()")
+	}
+	if wildcard != "" {
+		weaknesses = append(weaknesses, fmt.Sprintf("%q allows scripts from any host", wildcard))
+	}
+	return weaknesses
+}
+
+// analyzeTLS checks the expiry of the certificate and returns the points to
+// deduct. The crawler negotiates TLS 1.2 or newer and rejects expired
+// certificates unless verification is turned off for the host, so those
+// pages fail to fetch instead of reaching the analyzer.
+func (a *Analyzer) analyzeTLS(result *crawler.CrawlResult, score *SEOScore) float64 {
+	cert := result.TLS
+	if cert == nil {
+		return 0
+	}
+
+	penalty := 0.0
+	days := cert.DaysRemaining(time.Now())
+	switch {
+	case days < 0:
+		// Only fetched with certificate verification turned off
+		penalty += 20
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "critical",
+			Category:    "security",
+			Title:       "TLS Certificate Expired",
+			Description: fmt.Sprintf("The certificate issued by %s expired on %s", cert.Issuer, cert.NotAfter.Format("2006-01-02")),
+			Impact:      "Browsers block the page with a security warning",
+			HowToFix:    "Renew the certificate and enable automatic renewal",
+		})
+	case days < certExpiryCriticalDays:
+		penalty += 15
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "security",
+			Title:       "TLS Certificate Expires Soon",
+			Description: fmt.Sprintf("The certificate issued by %s expires in %d day(s) on %s", cert.Issuer, days, cert.NotAfter.Format("2006-01-02")),
+			Impact:      "Browsers will block the page once the certificate has expired",
+			HowToFix:    "Renew the certificate and check that automatic renewal works",
+		})
+	case days < certExpiryWarningDays:
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "TLS Certificate Expires Soon",
+			Description: fmt.Sprintf("The certificate issued by %s expires in %d days on %s", cert.Issuer, days, cert.NotAfter.Format("2006-01-02")),
+			Impact:      "Browsers will block the page once the certificate has expired",
+			HowToFix:    "Renew the certificate and check that automatic renewal works",
+		})
+	}
+
+	if penalty == 0 {
+		score.Breakdown["tls"] = 20
+	}
+	return penalty
+}
+
+// analyzeMixedContent checks for HTTP resources on an HTTPS page and
+// returns the points to deduct
+func (a *Analyzer) analyzeMixedContent(result *crawler.CrawlResult, score *SEOScore) float64 {
+	var active, passive []string
+	for _, m := range result.MixedContent {
+		entry := fmt.Sprintf("<%s> %s", m.Element, m.URL)
+		if m.Active {
+			active = append(active, entry)
+		} else {
+			passive = append(passive, entry)
+		}
+	}
+
+	penalty := 0.0
+	if len(active) > 0 {
+		penalty += 20
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "critical",
+			Category:    "security",
+			Title:       "Active Mixed Content",
+			Description: fmt.Sprintf("%d script(s), stylesheet(s) or frame(s) are loaded over HTTP: %s", len(active), strings.Join(limitStrings(uniqueStrings(active), 5), ", ")),
+			Impact:      "Browsers block these resources, which can break layout and functionality",
+			HowToFix:    "Load all resources over HTTPS",
+		})
+	}
+	if len(passive) > 0 {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Passive Mixed Content",
+			Description: fmt.Sprintf("%d image(s) or media file(s) are loaded over HTTP: %s", len(passive), strings.Join(limitStrings(uniqueStrings(passive), 5), ", ")),
+			Impact:      "Browsers show the page as not fully secure or block the resources",
+			HowToFix:    "Load all images and media over HTTPS",
+		})
+	}
+
+	if penalty == 0 {
+		score.Breakdown["mixed_content"] = 20
+	}
+	return math.Min(20, penalty)
+}
+
+// analyzeCookies checks the flags of the cookies set by the page and returns
+// the points to deduct
+func (a *Analyzer) analyzeCookies(result *crawler.CrawlResult, score *SEOScore) float64 {
+	if len(result.Cookies) == 0 {
+		score.Breakdown["cookies"] = 10
+		return 0
+	}
+
+	var insecure, sameSiteNone, scriptable []string
+	for _, cookie := range result.Cookies {
+		if !cookie.Secure {
+			if cookie.SameSite == "None" {
+				sameSiteNone = append(sameSiteNone, cookie.Name)
+			} else if result.HasHTTPS {
+				insecure = append(insecure, cookie.Name)
+			}
+		}
+		if !cookie.HttpOnly {
+			scriptable = append(scriptable, cookie.Name)
+		}
+	}
+
+	penalty := 0.0
+	if len(sameSiteNone) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "security",
+			Title:       "SameSite=None Cookies Without Secure",
+			Description: fmt.Sprintf("Cookie(s) %s use SameSite=None without the Secure flag", strings.Join(limitStrings(uniqueStrings(sameSiteNone), 5), ", ")),
+			Impact:      "Browsers reject these cookies",
+			HowToFix:    "Add the Secure flag to every SameSite=None cookie",
+		})
+	}
+	if len(insecure) > 0 {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "security",
+			Title:       "Cookies Without Secure Flag",
+			Description: fmt.Sprintf("Cookie(s) %s can be sent over unencrypted connections", strings.Join(limitStrings(uniqueStrings(insecure), 5), ", ")),
+			Impact:      "Session cookies can be intercepted on HTTP requests",
+			HowToFix:    "Set the Secure flag on all cookies of HTTPS sites",
+		})
+	}
+	if len(scriptable) > 0 {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "security",
+			Title:       "Cookies Without HttpOnly Flag",
+			Description: fmt.Sprintf("Cookie(s) %s are readable by JavaScript", strings.Join(limitStrings(uniqueStrings(scriptable), 5), ", ")),
+			Impact:      "Session cookies readable by scripts can be stolen through XSS",
+			Effort:      "low",
+			Potential:   0,
+		})
+	}
+
+	if penalty == 0 {
+		score.Breakdown["cookies"] = 10
+	}
+	return math.Min(10, penalty)
+}
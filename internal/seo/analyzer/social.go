@@ -0,0 +1,299 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// Preview image limits shared by Facebook, LinkedIn and X
+const (
+	socialImageMinWidth  = 200
+	socialImageMinHeight = 200
+	socialImageWidth     = 1200
+	socialImageHeight    = 630
+	socialImageMaxBytes  = 5 * 1024 * 1024
+	socialImageRatioMin  = 1.5
+	socialImageRatioMax  = 2.2
+	socialDescriptionMax = 200
+)
+
+// validTwitterCards are the card types X renders
+var validTwitterCards = map[string]bool{
+	"summary":             true,
+	"summary_large_image": true,
+	"app":                 true,
+	"player":              true,
+}
+
+// analyzeSocial checks the Open Graph and Twitter Card tags that control how
+// links to the page look when shared
+func (a *Analyzer) analyzeSocial(result *crawler.CrawlResult, score *SEOScore) float64 {
+	points := 100.0
+	og := result.OpenGraph
+	card := result.TwitterCard
+
+	// og:title
+	if og.Title == "" {
+		points -= 20
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "social",
+			Title:       "Missing og:title",
+			Description: "No Open Graph title found",
+			Impact:      "Platforms guess the title from the page, often poorly",
+			HowToFix:    `Add <meta property="og:title" content="..."> with a concise, compelling title`,
+		})
+	} else {
+		score.Breakdown["og_title"] = 20
+	}
+
+	// og:description
+	if og.Description == "" {
+		points -= 10
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "social",
+			Title:       "Missing og:description",
+			Description: "No Open Graph description found",
+			Impact:      "Shared links show no or a random text snippet",
+			Effort:      "low",
+			Potential:   10,
+		})
+	} else if len(og.Description) > socialDescriptionMax {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "og:description Too Long",
+			Description: fmt.Sprintf("Description is %d characters and will be truncated", len(og.Description)),
+			Impact:      "The key message may be cut off in previews",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else {
+		score.Breakdown["og_description"] = 10
+	}
+
+	// og:image
+	points -= a.analyzeSocialImage(result, score)
+
+	// og:url should match the canonical URL
+	if og.URL == "" {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "Missing og:url",
+			Description: "No Open Graph URL found",
+			Impact:      "Likes and shares may be split across URL variants",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else if result.CanonicalURL != "" && strings.TrimSuffix(og.URL, "/") != strings.TrimSuffix(result.CanonicalURL, "/") {
+		points -= 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "social",
+			Title:       "og:url Differs From Canonical",
+			Description: fmt.Sprintf("og:url is %s but the canonical URL is %s", og.URL, result.CanonicalURL),
+			Impact:      "Share counts and previews are attributed to a different URL",
+			HowToFix:    "Set og:url to the canonical URL of the page",
+		})
+	} else {
+		score.Breakdown["og_url"] = 5
+	}
+
+	// og:type and og:site_name
+	if og.Type == "" {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "Missing og:type",
+			Description: "No Open Graph type found (e.g. website, article, product)",
+			Impact:      "Platforms fall back to \"website\"",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else {
+		score.Breakdown["og_type"] = 5
+	}
+	if og.SiteName == "" {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "Missing og:site_name",
+			Description: "No Open Graph site name found",
+			Impact:      "Previews do not show the brand name",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else {
+		score.Breakdown["og_site_name"] = 5
+	}
+
+	// Twitter Card
+	if card.Card == "" {
+		points -= 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "Missing twitter:card",
+			Description: "No Twitter Card type found",
+			Impact:      "X shows a small summary card instead of a large image preview",
+			Effort:      "low",
+			Potential:   5,
+		})
+	} else if !validTwitterCards[card.Card] {
+		points -= 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "social",
+			Title:       "Invalid twitter:card",
+			Description: fmt.Sprintf("Card type %q is not supported", card.Card),
+			Impact:      "X ignores the card and shows a plain link",
+			HowToFix:    "Use summary or summary_large_image",
+		})
+	} else {
+		score.Breakdown["twitter_card"] = 5
+	}
+
+	return math.Max(0, points)
+}
+
+// analyzeSocialImage checks the first og:image and returns the points to
+// deduct from the social score
+func (a *Analyzer) analyzeSocialImage(result *crawler.CrawlResult, score *SEOScore) float64 {
+	if len(result.OpenGraph.Images) == 0 || result.OpenGraph.Images[0].URL == "" {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "social",
+			Title:       "Missing og:image",
+			Description: "No Open Graph image found",
+			Impact:      "Shared links appear without a preview image and get far fewer clicks",
+			HowToFix:    fmt.Sprintf(`Add <meta property="og:image" content="https://..."> with an image of %dx%d pixels`, socialImageWidth, socialImageHeight),
+		})
+		return 30
+	}
+
+	img := result.OpenGraph.Images[0]
+	if !img.Checked {
+		score.Breakdown["og_image"] = 30
+		return 0
+	}
+
+	// Unreachable image
+	if img.StatusCode != 200 {
+		description := fmt.Sprintf("%s returned status %d", img.URL, img.StatusCode)
+		if img.StatusCode == 0 {
+			description = fmt.Sprintf("%s could not be fetched: %s", img.URL, img.Error)
+		}
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "social",
+			Title:       "Broken og:image",
+			Description: description,
+			Impact:      "Shared links appear without a preview image",
+			HowToFix:    "Point og:image to a publicly reachable absolute image URL",
+		})
+		return 30
+	}
+	if img.ContentType != "" && !strings.HasPrefix(strings.ToLower(img.ContentType), "image/") {
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "social",
+			Title:       "og:image Is Not an Image",
+			Description: fmt.Sprintf("%s is served as %s", img.URL, img.ContentType),
+			Impact:      "Platforms reject the preview image",
+			HowToFix:    "Reference an image file directly instead of an HTML page",
+		})
+		return 30
+	}
+
+	penalty := 0.0
+	if img.Size > socialImageMaxBytes {
+		penalty += 5
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "medium",
+			Category:    "social",
+			Title:       "og:image Too Large",
+			Description: fmt.Sprintf("Image is %.1f MB (LinkedIn limit: 5 MB)", float64(img.Size)/1024/1024),
+			Impact:      "Some platforms drop the preview image",
+			HowToFix:    "Compress the image or serve it as JPEG or WebP",
+		})
+	}
+
+	width, height := img.ActualWidth, img.ActualHeight
+	if width == 0 || height == 0 {
+		// Undecodable format, fall back to the declared size
+		width, height = img.Width, img.Height
+	}
+	switch {
+	case width == 0 || height == 0:
+		penalty += 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "Missing og:image Dimensions",
+			Description: "og:image:width and og:image:height are not declared",
+			Impact:      "Platforms render the first share without an image while they fetch it",
+			Effort:      "low",
+			Potential:   5,
+		})
+	case width < socialImageMinWidth || height < socialImageMinHeight:
+		penalty += 20
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "social",
+			Title:       "og:image Too Small",
+			Description: fmt.Sprintf("Image is %dx%d pixels (minimum: %dx%d)", width, height, socialImageMinWidth, socialImageMinHeight),
+			Impact:      "Platforms ignore the image or show a tiny thumbnail",
+			HowToFix:    fmt.Sprintf("Use an image of %dx%d pixels", socialImageWidth, socialImageHeight),
+		})
+	case width < socialImageWidth || height < socialImageHeight:
+		penalty += 5
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "social",
+			Title:       "Low-Resolution og:image",
+			Description: fmt.Sprintf("Image is %dx%d pixels (recommended: %dx%d)", width, height, socialImageWidth, socialImageHeight),
+			Impact:      "Large previews look blurry or fall back to a small card",
+			Effort:      "low",
+			Potential:   5,
+		})
+	default:
+		if ratio := float64(width) / float64(height); ratio < socialImageRatioMin || ratio > socialImageRatioMax {
+			penalty += 5
+			score.Opportunities = append(score.Opportunities, Opportunity{
+				Priority:    "low",
+				Category:    "social",
+				Title:       "og:image Aspect Ratio",
+				Description: fmt.Sprintf("Image is %dx%d pixels (ratio %.2f:1, recommended 1.91:1)", width, height, ratio),
+				Impact:      "Platforms crop the image, possibly cutting off text or logos",
+				Effort:      "low",
+				Potential:   5,
+			})
+		}
+	}
+
+	if img.ActualWidth > 0 && img.Width > 0 && (img.Width != img.ActualWidth || img.Height != img.ActualHeight) {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "low",
+			Category:    "social",
+			Title:       "og:image Dimensions Mismatch",
+			Description: fmt.Sprintf("Declared %dx%d but the image is %dx%d pixels", img.Width, img.Height, img.ActualWidth, img.ActualHeight),
+			Impact:      "Platforms may reserve the wrong space for the preview",
+			Effort:      "low",
+			Potential:   0,
+		})
+	}
+
+	if penalty == 0 {
+		score.Breakdown["og_image"] = 30
+	}
+	return penalty
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// analyzeSoft404 reports pages that return 200 but look like error pages
+// and returns the points to deduct from the technical score
+func (a *Analyzer) analyzeSoft404(result *crawler.CrawlResult, score *SEOScore) float64 {
+	soft := result.Soft404
+	if soft == nil {
+		return 0
+	}
+	penalty := 0.0
+
+	if soft.Detected {
+		penalty += 20
+		var reasons []string
+		for _, signal := range soft.Signals {
+			switch signal {
+			case crawler.Soft404ErrorPhrase:
+				reasons = append(reasons, fmt.Sprintf("the title or H1 says %q", soft.Phrase))
+			case crawler.Soft404MatchesNotFound:
+				reasons = append(reasons, fmt.Sprintf("the content is %.0f%% identical to the page returned for a non-existent URL", soft.Similarity*100))
+			case crawler.Soft404ThinContent:
+				reasons = append(reasons, fmt.Sprintf("only %d words of unique content", soft.UniqueWords))
+			}
+		}
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "critical",
+			Category:    "technical",
+			Title:       "Soft 404",
+			Description: fmt.Sprintf("The page returns status 200 but looks like an error page: %s", strings.Join(reasons, ", ")),
+			Impact:      "Google treats soft 404s as errors, wastes crawl budget on them and keeps them out of the index",
+			HowToFix:    "Return 404 or 410 for deleted content, or redirect to a relevant replacement such as the product category",
+		})
+	} else if containsSignal(soft.Signals, crawler.Soft404ThinContent) {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "content",
+			Title:       "Little Unique Content",
+			Description: fmt.Sprintf("Only %d distinct words of the main content are not part of the site template", soft.UniqueWords),
+			Impact:      "Pages without content of their own may be classified as soft 404 or thin content",
+			Effort:      "medium",
+			Potential:   0,
+		})
+	}
+
+	// The site answers unknown URLs with 200
+	if soft.ProbeStatus == http.StatusOK {
+		penalty += 10
+		score.Issues = append(score.Issues, Issue{
+			Severity:    "high",
+			Category:    "technical",
+			Title:       "Non-Existent URLs Return 200",
+			Description: fmt.Sprintf("The made-up URL %s returned status 200 instead of 404", soft.ProbeURL),
+			Impact:      "Every mistyped or deleted URL becomes an indexable soft 404",
+			HowToFix:    "Configure the server or CMS to return 404 for unknown URLs",
+		})
+	}
+
+	return penalty
+}
+
+// containsSignal reports whether signals contains signal
+func containsSignal(signals []string, signal string) bool {
+	for _, s := range signals {
+		if s == signal {
+			return true
+		}
+	}
+	return false
+}
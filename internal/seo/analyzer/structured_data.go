@@ -0,0 +1,343 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/EricFreesoul/phoenix-feuer-os/internal/seo/crawler"
+)
+
+// StructuredDataValidation is the validation result of one structured data item
+type StructuredDataValidation struct {
+	Type     string   `json:"type"`
+	Format   string   `json:"format"`
+	Schema   string   `json:"schema,omitempty"` // validated rule set, empty if the type is not checked
+	Eligible bool     `json:"eligible"`         // all required properties present
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// schemaRule lists the properties search engines expect for a schema.org type
+type schemaRule struct {
+	required    []string
+	recommended []string
+	// anyOf groups of which at least one property must be present
+	anyOf [][]string
+	// check validates nested entities and returns errors and warnings
+	check func(props map[string]interface{}) ([]string, []string)
+}
+
+// schemaRules are the supported rich result types
+var schemaRules = map[string]schemaRule{
+	"Organization": {
+		required:    []string{"name"},
+		recommended: []string{"url", "logo", "sameAs", "contactPoint"},
+	},
+	"LocalBusiness": {
+		required:    []string{"name", "address"},
+		recommended: []string{"telephone", "url", "image", "geo", "openingHoursSpecification", "priceRange"},
+		check:       checkLocalBusiness,
+	},
+	"Product": {
+		required:    []string{"name"},
+		recommended: []string{"image", "description", "brand", "sku"},
+		anyOf:       [][]string{{"offers", "review", "aggregateRating"}},
+		check:       checkProduct,
+	},
+	"Article": {
+		required:    []string{"headline"},
+		recommended: []string{"image", "datePublished", "dateModified", "author", "publisher"},
+	},
+	"FAQPage": {
+		required: []string{"mainEntity"},
+		check:    checkFAQPage,
+	},
+	"BreadcrumbList": {
+		required: []string{"itemListElement"},
+		check:    checkBreadcrumbList,
+	},
+	"Event": {
+		required:    []string{"name", "startDate", "location"},
+		recommended: []string{"endDate", "description", "image", "eventStatus", "eventAttendanceMode", "offers", "organizer", "performer"},
+	},
+	"Recipe": {
+		required:    []string{"name", "image"},
+		recommended: []string{"author", "datePublished", "description", "recipeIngredient", "recipeInstructions", "totalTime", "recipeYield", "nutrition", "aggregateRating"},
+	},
+}
+
+// schemaSubtypes maps common schema.org subtypes to the rule set they are validated with
+var schemaSubtypes = map[string]string{
+	"Corporation":                 "Organization",
+	"NGO":                         "Organization",
+	"EducationalOrganization":     "Organization",
+	"OnlineBusiness":              "Organization",
+	"OnlineStore":                 "Organization",
+	"Restaurant":                  "LocalBusiness",
+	"FoodEstablishment":           "LocalBusiness",
+	"CafeOrCoffeeShop":            "LocalBusiness",
+	"Bakery":                      "LocalBusiness",
+	"Store":                       "LocalBusiness",
+	"AutoRepair":                  "LocalBusiness",
+	"Dentist":                     "LocalBusiness",
+	"MedicalBusiness":             "LocalBusiness",
+	"HealthAndBeautyBusiness":     "LocalBusiness",
+	"HomeAndConstructionBusiness": "LocalBusiness",
+	"Electrician":                 "LocalBusiness",
+	"Plumber":                     "LocalBusiness",
+	"RoofingContractor":           "LocalBusiness",
+	"HVACBusiness":                "LocalBusiness",
+	"ProfessionalService":         "LocalBusiness",
+	"LegalService":                "LocalBusiness",
+	"RealEstateAgent":             "LocalBusiness",
+	"LodgingBusiness":             "LocalBusiness",
+	"Hotel":                       "LocalBusiness",
+	"IndividualProduct":           "Product",
+	"ProductModel":                "Product",
+	"NewsArticle":                 "Article",
+	"BlogPosting":                 "Article",
+	"TechArticle":                 "Article",
+	"Report":                      "Article",
+	"ScholarlyArticle":            "Article",
+	"BusinessEvent":               "Event",
+	"EducationEvent":              "Event",
+	"Festival":                    "Event",
+	"MusicEvent":                  "Event",
+	"SportsEvent":                 "Event",
+	"TheaterEvent":                "Event",
+}
+
+// ValidateStructuredData checks a structured data item against the rules of its schema.org type
+func ValidateStructuredData(item crawler.StructuredDataItem) StructuredDataValidation {
+	validation := StructuredDataValidation{
+		Type:   strings.Join(item.Types, ", "),
+		Format: item.Format,
+	}
+	if item.Error != "" {
+		validation.Errors = append(validation.Errors, item.Error)
+		return validation
+	}
+	if len(item.Types) == 0 {
+		validation.Errors = append(validation.Errors, "no schema.org type specified")
+		return validation
+	}
+
+	var rule schemaRule
+	for _, t := range item.Types {
+		name := t
+		if alias, ok := schemaSubtypes[t]; ok {
+			name = alias
+		}
+		if r, ok := schemaRules[name]; ok {
+			validation.Schema = name
+			rule = r
+			break
+		}
+	}
+	if validation.Schema == "" {
+		// Types without rich result rules are valid as long as they parse
+		validation.Eligible = true
+		return validation
+	}
+
+	for _, prop := range rule.required {
+		if !hasProperty(item.Properties, prop) {
+			validation.Errors = append(validation.Errors, fmt.Sprintf("missing required property %q", prop))
+		}
+	}
+	for _, group := range rule.anyOf {
+		found := false
+		for _, prop := range group {
+			if hasProperty(item.Properties, prop) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			validation.Errors = append(validation.Errors, fmt.Sprintf("one of %s is required", strings.Join(group, ", ")))
+		}
+	}
+	for _, prop := range rule.recommended {
+		if !hasProperty(item.Properties, prop) {
+			validation.Warnings = append(validation.Warnings, fmt.Sprintf("missing recommended property %q", prop))
+		}
+	}
+	if rule.check != nil {
+		errs, warnings := rule.check(item.Properties)
+		validation.Errors = append(validation.Errors, errs...)
+		validation.Warnings = append(validation.Warnings, warnings...)
+	}
+
+	validation.Eligible = len(validation.Errors) == 0
+	return validation
+}
+
+// checkLocalBusiness validates the postal address of a local business
+func checkLocalBusiness(props map[string]interface{}) ([]string, []string) {
+	var warnings []string
+	for _, address := range entities(props["address"]) {
+		for _, prop := range []string{"streetAddress", "addressLocality", "postalCode", "addressCountry"} {
+			if !hasProperty(address, prop) {
+				warnings = append(warnings, fmt.Sprintf("address is missing %q", prop))
+			}
+		}
+	}
+	return nil, warnings
+}
+
+// checkProduct validates the offers of a product
+func checkProduct(props map[string]interface{}) ([]string, []string) {
+	var errs []string
+	for i, offer := range entities(props["offers"]) {
+		if !hasProperty(offer, "price") && !hasProperty(offer, "lowPrice") && !hasProperty(offer, "priceSpecification") {
+			errs = append(errs, fmt.Sprintf("offer %d is missing \"price\"", i+1))
+		}
+		if !hasProperty(offer, "priceCurrency") && !hasProperty(offer, "priceSpecification") {
+			errs = append(errs, fmt.Sprintf("offer %d is missing \"priceCurrency\"", i+1))
+		}
+	}
+	return errs, nil
+}
+
+// checkFAQPage validates that every question has a name and an answer text
+func checkFAQPage(props map[string]interface{}) ([]string, []string) {
+	var errs []string
+	for i, question := range entities(props["mainEntity"]) {
+		if !hasProperty(question, "name") {
+			errs = append(errs, fmt.Sprintf("question %d is missing \"name\"", i+1))
+		}
+		answers := entities(question["acceptedAnswer"])
+		if len(answers) == 0 {
+			errs = append(errs, fmt.Sprintf("question %d is missing \"acceptedAnswer\"", i+1))
+			continue
+		}
+		for _, answer := range answers {
+			if !hasProperty(answer, "text") {
+				errs = append(errs, fmt.Sprintf("answer to question %d is missing \"text\"", i+1))
+			}
+		}
+	}
+	return errs, nil
+}
+
+// checkBreadcrumbList validates the list items of a breadcrumb trail. The
+// last item may omit its URL because it is the current page.
+func checkBreadcrumbList(props map[string]interface{}) ([]string, []string) {
+	var errs []string
+	items := entities(props["itemListElement"])
+	for i, item := range items {
+		if !hasProperty(item, "position") {
+			errs = append(errs, fmt.Sprintf("breadcrumb %d is missing \"position\"", i+1))
+		}
+		target := entities(item["item"])
+		if !hasProperty(item, "name") && (len(target) == 0 || !hasProperty(target[0], "name")) {
+			errs = append(errs, fmt.Sprintf("breadcrumb %d is missing \"name\"", i+1))
+		}
+		if i < len(items)-1 && !hasProperty(item, "item") {
+			errs = append(errs, fmt.Sprintf("breadcrumb %d is missing \"item\"", i+1))
+		}
+	}
+	return errs, nil
+}
+
+// hasProperty reports whether a property is present and not empty
+func hasProperty(props map[string]interface{}, name string) bool {
+	value, ok := props[name]
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// entities returns the nested entities of a property value. Plain values
+// such as a URL string are wrapped so that single-value checks still work.
+func entities(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var result []map[string]interface{}
+		for _, entry := range v {
+			result = append(result, entities(entry)...)
+		}
+		return result
+	case string:
+		if v != "" {
+			return []map[string]interface{}{{"@id": v}}
+		}
+	}
+	return nil
+}
+
+// analyzeStructuredData validates the structured data of a page and returns
+// the points to deduct from the on-page score
+func (a *Analyzer) analyzeStructuredData(result *crawler.CrawlResult, score *SEOScore) float64 {
+	if len(result.StructuredData) == 0 {
+		score.Opportunities = append(score.Opportunities, Opportunity{
+			Priority:    "medium",
+			Category:    "structured_data",
+			Title:       "No Structured Data",
+			Description: "Page contains no JSON-LD, Microdata or RDFa markup",
+			Impact:      "Page is not eligible for rich results such as stars, FAQs or breadcrumbs",
+			Effort:      "medium",
+			Potential:   5,
+		})
+		return 0
+	}
+
+	penalty := 0.0
+	for _, item := range result.StructuredData {
+		validation := ValidateStructuredData(item)
+		score.StructuredData = append(score.StructuredData, validation)
+
+		switch {
+		case item.Error != "":
+			penalty += 5
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "high",
+				Category:    "structured_data",
+				Title:       "Invalid Structured Data",
+				Description: fmt.Sprintf("%s markup could not be parsed: %s", item.Format, item.Error),
+				Impact:      "Search engines ignore the markup entirely",
+				HowToFix:    "Fix the syntax and test the page with the Rich Results Test",
+			})
+		case len(validation.Errors) > 0:
+			penalty += 5
+			score.Issues = append(score.Issues, Issue{
+				Severity:    "medium",
+				Category:    "structured_data",
+				Title:       fmt.Sprintf("Incomplete %s Markup", validation.Type),
+				Description: strings.Join(validation.Errors, "; "),
+				Impact:      "Page is not eligible for the corresponding rich result",
+				HowToFix:    "Add the missing properties to the structured data",
+			})
+		case len(validation.Warnings) > 0:
+			score.Opportunities = append(score.Opportunities, Opportunity{
+				Priority:    "low",
+				Category:    "structured_data",
+				Title:       fmt.Sprintf("Enhance %s Markup", validation.Type),
+				Description: strings.Join(validation.Warnings, "; "),
+				Impact:      "More complete markup improves rich result appearance",
+				Effort:      "low",
+				Potential:   2,
+			})
+		}
+	}
+
+	if penalty == 0 {
+		score.Breakdown["structured_data"] = 10
+	}
+
+	return math.Min(10, penalty)
+}
@@ -0,0 +1,245 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// redactedValue replaces secrets in crawl results
+const redactedValue = "REDACTED"
+
+// Access holds the credentials and overrides needed to crawl a protected
+// site, such as a staging environment behind basic auth or a login. The
+// credentials, cookies and headers are only sent to the hosts in Hosts and
+// never appear in crawl results.
+type Access struct {
+	// Hosts receive the credentials; subdomains must be listed separately
+	Hosts    []string
+	Username string
+	Password string
+	// Cookies seed the cookie jar, e.g. a session cookie of a login. Cookies
+	// set by the site during the crawl are kept in the jar as well.
+	Cookies []*http.Cookie
+	// Headers are added to every request and replace the default ones
+	Headers http.Header
+	// Resolve maps a host or host:port to the IP address to connect to
+	// instead of its DNS answer, like curl --resolve. TLS still verifies the
+	// certificate for the host name. It does not apply behind a proxy. Only
+	// hosts in Hosts can be overridden.
+	Resolve map[string]string
+	// Proxy selects one of the named proxies of the crawler's egress
+	Proxy string
+	// InsecureSkipVerify accepts invalid certificates of the hosts, such as
+	// self-signed ones of staging sites
+	InsecureSkipVerify bool
+}
+
+// String describes the access without its secrets
+func (a *Access) String() string {
+	return fmt.Sprintf("access for %s (credentials redacted)", strings.Join(a.Hosts, ", "))
+}
+
+// checkpointNamespace returns the checkpoint subdirectory of crawls with
+// the access. It is derived from the hosts and credentials, so neither a
+// public crawl nor a crawl with other credentials can resume or re-crawl
+// pages fetched with them. Crawl IDs cannot contain dots, so it cannot
+// clash with a crawl directory.
+func (a *Access) checkpointNamespace() string {
+	hash := sha256.New()
+	write := func(values ...string) {
+		for _, value := range values {
+			hash.Write([]byte(value))
+			hash.Write([]byte{0})
+		}
+	}
+	hosts := make([]string, len(a.Hosts))
+	for i, host := range a.Hosts {
+		hosts[i] = strings.ToLower(host)
+	}
+	slices.Sort(hosts)
+	write(hosts...)
+	write(a.Username, a.Password)
+	for _, cookie := range a.Cookies {
+		write(cookie.Name, cookie.Value)
+	}
+	names := make([]string, 0, len(a.Headers))
+	for name := range a.Headers {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		write(name)
+		write(a.Headers.Values(name)...)
+	}
+	return filepath.Join(".access", hex.EncodeToString(hash.Sum(nil))[:32])
+}
+
+// WithAccess returns a crawler for a protected site. It has the settings of
+// c, shares its rate limits and egress configuration and uses its own
+// connections, cookie jar and caches, so pages seen with credentials never
+// mix with public ones. Its checkpoints are kept apart from public crawls.
+// Close releases its connections once the crawl is done.
+func (c *Crawler) WithAccess(access *Access) (*Crawler, error) {
+	if len(access.Hosts) == 0 {
+		return nil, fmt.Errorf("access needs at least one host")
+	}
+	hosts := make(map[string]bool, len(access.Hosts))
+	for _, host := range access.Hosts {
+		hosts[strings.ToLower(host)] = true
+	}
+	resolve := make(map[string]string, len(access.Resolve))
+	for host, ip := range access.Resolve {
+		if !hosts[resolveHost(host)] {
+			return nil, fmt.Errorf("cannot resolve %s: not one of the access hosts", host)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid IP address %q for %s", ip, host)
+		}
+		resolve[strings.ToLower(host)] = ip
+	}
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	for host := range hosts {
+		jar.SetCookies(&url.URL{Scheme: "https", Host: host, Path: "/"}, access.Cookies)
+	}
+
+	transport, err := c.egress.newTransport(access.Proxy, resolve, false)
+	if err != nil {
+		return nil, err
+	}
+	roundTripper := &accessTransport{base: transport, hostBase: transport, access: access, hosts: hosts}
+	if access.InsecureSkipVerify {
+		// A second pool, so certificates of other hosts are still verified
+		roundTripper.hostBase, err = c.egress.newTransport(access.Proxy, resolve, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scoped := NewCrawler(c.userAgent, c.timeout, c.maxDepth)
+	scoped.respectRobotsTxt = c.respectRobotsTxt
+	scoped.crawlDelay = c.crawlDelay
+	scoped.maxConcurrent = c.maxConcurrent
+	scoped.maxRetries = c.maxRetries
+	scoped.acceptLanguage = c.acceptLanguage
+	scoped.mobileParity = c.mobileParity
+	scoped.checkAssets = c.checkAssets
+	scoped.soft404Probe = c.soft404Probe
+	if c.checkpointDir != "" {
+		scoped.checkpointDir = filepath.Join(c.checkpointDir, access.checkpointNamespace())
+	}
+	scoped.rateLimiter = c.rateLimiter
+	scoped.egress = c.egress
+	scoped.redactCookies = true
+	for _, client := range []*http.Client{scoped.client, scoped.pageClient} {
+		client.Transport = roundTripper
+		client.Jar = jar
+	}
+	return scoped, nil
+}
+
+// Close closes the idle connections of the crawler. A crawler returned by
+// WithAccess has its own connections, which would otherwise stay open until
+// they time out.
+func (c *Crawler) Close() {
+	c.client.CloseIdleConnections()
+}
+
+// resolveHost returns the lowercase host of a Resolve key, which is a host or
+// host:port
+func resolveHost(key string) string {
+	if host, _, err := net.SplitHostPort(key); err == nil {
+		key = host
+	}
+	return strings.ToLower(key)
+}
+
+// resolveAddr returns the address to dial for host:port, applying the
+// overrides by host:port first and by host second
+func resolveAddr(resolve map[string]string, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.ToLower(host)
+	if ip, ok := resolve[net.JoinHostPort(host, port)]; ok {
+		return net.JoinHostPort(ip, port)
+	}
+	if ip, ok := resolve[host]; ok {
+		return net.JoinHostPort(ip, port)
+	}
+	return addr
+}
+
+// accessTransport adds the credentials and headers of an access to the
+// requests to its hosts
+type accessTransport struct {
+	base http.RoundTripper
+	// hostBase sends the requests to the hosts of the access; it skips
+	// certificate verification if the access asks for it
+	hostBase http.RoundTripper
+	access   *Access
+	hosts    map[string]bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *accessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hosts[strings.ToLower(req.URL.Hostname())] {
+		return t.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	for name, values := range t.access.Headers {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			continue
+		}
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if t.access.Username != "" || t.access.Password != "" {
+		req.SetBasicAuth(t.access.Username, t.access.Password)
+	}
+	return t.hostBase.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the base transports
+func (t *accessTransport) CloseIdleConnections() {
+	for _, base := range []http.RoundTripper{t.base, t.hostBase} {
+		if closer, ok := base.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+}
+
+// responseHeaders returns the headers of a page response. An authenticated
+// crawl replaces the cookie values, which may be session tokens.
+func (c *Crawler) responseHeaders(resp *http.Response) http.Header {
+	headers := resp.Header.Clone()
+	if !c.redactCookies {
+		return headers
+	}
+	for i, value := range headers.Values("Set-Cookie") {
+		name, rest, _ := strings.Cut(value, "=")
+		attributes := ""
+		if _, attrs, ok := strings.Cut(rest, ";"); ok {
+			attributes = ";" + attrs
+		}
+		headers["Set-Cookie"][i] = name + "=" + redactedValue + attributes
+	}
+	return headers
+}
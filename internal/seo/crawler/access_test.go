@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// accessRecorder is a test server that records the credentials it receives
+type accessRecorder struct {
+	srv *httptest.Server
+	mu  sync.Mutex
+	// seen holds the Authorization, Cookie and X-Preview-Token headers of
+	// the requests, except for robots.txt
+	seen []string
+}
+
+func newAccessRecorder(t *testing.T, pages map[string]string) *accessRecorder {
+	rec := &accessRecorder{}
+	rec.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		rec.mu.Lock()
+		rec.seen = append(rec.seen, r.Header.Get("Authorization")+r.Header.Get("Cookie")+r.Header.Get("X-Preview-Token"))
+		rec.mu.Unlock()
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if location, ok := strings.CutPrefix(body, "redirect:"); ok {
+			http.Redirect(w, r, location, http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(rec.srv.Close)
+	return rec
+}
+
+// credentials returns the recorded credentials of the requests
+func (rec *accessRecorder) credentials() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]string(nil), rec.seen...)
+}
+
+func TestWithAccessSendsCredentialsOnlyToHosts(t *testing.T) {
+	// Both servers listen on 127.0.0.1; the other one is reached as
+	// localhost, so the two have different host names
+	other := newAccessRecorder(t, map[string]string{"/": testPage("Other")})
+	otherURL := strings.Replace(other.srv.URL, "127.0.0.1", "localhost", 1)
+	protected := newAccessRecorder(t, map[string]string{
+		"/":      testPage("Staging", otherURL+"/"),
+		"/leave": "redirect:" + otherURL + "/",
+	})
+
+	c := newTestCrawler()
+	scoped, err := c.WithAccess(&Access{
+		Hosts:    []string{"127.0.0.1"},
+		Username: "preview",
+		Password: "secret",
+		Cookies:  []*http.Cookie{{Name: "session", Value: "token"}},
+		Headers:  http.Header{"X-Preview-Token": {"abc"}},
+	})
+	if err != nil {
+		t.Fatalf("WithAccess() error = %v", err)
+	}
+	defer scoped.Close()
+
+	for _, pageURL := range []string{protected.srv.URL + "/", protected.srv.URL + "/leave", otherURL + "/"} {
+		if _, err := scoped.CrawlPage(context.Background(), pageURL); err != nil {
+			t.Fatalf("CrawlPage(%s) error = %v", pageURL, err)
+		}
+	}
+
+	got := protected.credentials()
+	if len(got) != 2 {
+		t.Fatalf("protected host got %d requests, want 2", len(got))
+	}
+	for _, credentials := range got {
+		if !strings.HasPrefix(credentials, "Basic ") || !strings.Contains(credentials, "session=token") || !strings.HasSuffix(credentials, "abc") {
+			t.Errorf("protected host got credentials %q, want basic auth, the cookie and the header", credentials)
+		}
+	}
+	got = other.credentials()
+	if len(got) != 2 {
+		t.Fatalf("other host got %d requests, want 2 (redirect and crawl)", len(got))
+	}
+	for _, credentials := range got {
+		if credentials != "" {
+			t.Errorf("other host got credentials %q", credentials)
+		}
+	}
+}
+
+func TestWithAccessResolve(t *testing.T) {
+	srv := newAccessRecorder(t, map[string]string{"/": testPage("Staging")})
+	port := srv.srv.Listener.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name    string
+		resolve map[string]string
+		wantErr string
+	}{
+		{
+			name:    "host",
+			resolve: map[string]string{"staging.invalid": "127.0.0.1"},
+		},
+		{
+			name:    "host and port",
+			resolve: map[string]string{net.JoinHostPort("STAGING.invalid", strconv.Itoa(port)): "127.0.0.1"},
+		},
+		{
+			name:    "other host",
+			resolve: map[string]string{"staging.invalid": "127.0.0.1", "www.example.com": "127.0.0.1"},
+			wantErr: "not one of the access hosts",
+		},
+		{
+			name:    "invalid IP address",
+			resolve: map[string]string{"staging.invalid": "staging"},
+			wantErr: "invalid IP address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoped, err := newTestCrawler().WithAccess(&Access{Hosts: []string{"staging.invalid"}, Resolve: tt.resolve})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("WithAccess() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithAccess() error = %v", err)
+			}
+			defer scoped.Close()
+
+			pageURL := (&url.URL{Scheme: "http", Host: net.JoinHostPort("staging.invalid", strconv.Itoa(port)), Path: "/"}).String()
+			result, err := scoped.CrawlPage(context.Background(), pageURL)
+			if err != nil {
+				t.Fatalf("CrawlPage() error = %v", err)
+			}
+			if result.Title != "Staging" {
+				t.Errorf("Title = %q, want the page of the resolved address", result.Title)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// checkpointInterval is the number of finished pages between two saves
+	// of the crawl state. Page results are written as soon as they finish.
+	checkpointInterval    = 25
+	checkpointStateFile   = "state.json"
+	checkpointRecordsFile = "pages.jsonl"
+)
+
+// crawlIDPattern restricts crawl IDs to safe directory names
+var crawlIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	// ErrCrawlRunning is returned when a crawl ID is resumed while it is
+	// still running
+	ErrCrawlRunning = errors.New("crawl is already running")
+	// ErrCrawlMismatch is returned when a crawl ID is resumed with a
+	// different start URL or crawl options than it was started with
+	ErrCrawlMismatch = errors.New("crawl was started with different options")
+)
+
+// runningCrawls holds the checkpoint directories of the crawls running in
+// this process. Two runs of the same crawl would both append to the records
+// and replace the state of each other. The lock lives in memory, so a crash
+// never leaves a stale lock that blocks the resume.
+var runningCrawls sync.Map
+
+// crawlState is the saved frontier of a site crawl
+type crawlState struct {
+	StartURL string
+	// Queue holds the URLs waiting to be crawled, including those that were
+	// in flight when the state was saved
+	Queue       []checkpointItem
+	Seen        []string
+	Skipped     map[string]int
+	SitemapURLs []SitemapURL
+	Errors      []string
+	Finished    bool
+	UpdatedAt   time.Time
+	// Definition is the configuration the crawl was started with
+	Definition crawlDefinition
+}
+
+// crawlDefinition holds the options that decide which URLs a crawl visits.
+// MaxPages is not part of it, a finished crawl may be extended.
+type crawlDefinition struct {
+	MaxDepth    int
+	UseSitemaps bool
+	Scope       CrawlScope
+	Normalize   URLNormalizer
+}
+
+// mismatch returns the name of the first option that differs from the
+// saved definition, or "" if both are the same
+func (d crawlDefinition) mismatch(saved crawlDefinition) string {
+	switch {
+	case d.MaxDepth != saved.MaxDepth:
+		return "max depth"
+	case d.UseSitemaps != saved.UseSitemaps:
+		return "use sitemaps"
+	case !slices.Equal(d.Scope.IncludePaths, saved.Scope.IncludePaths),
+		!slices.Equal(d.Scope.ExcludePaths, saved.Scope.ExcludePaths),
+		d.Scope.Subdomains != saved.Scope.Subdomains,
+		d.Scope.MaxURLParams != saved.Scope.MaxURLParams:
+		return "scope"
+	case (d.Normalize.TrackingParams == nil) != (saved.Normalize.TrackingParams == nil),
+		!slices.Equal(d.Normalize.TrackingParams, saved.Normalize.TrackingParams),
+		d.Normalize.TrailingSlash != saved.Normalize.TrailingSlash,
+		!maps.Equal(d.Normalize.HostAliases, saved.Normalize.HostAliases),
+		d.Normalize.LowercasePath != saved.Normalize.LowercasePath,
+		d.Normalize.SortQuery != saved.Normalize.SortQuery:
+		return "URL normalization"
+	}
+	return ""
+}
+
+// checkpointItem is a saved frontier item
+type checkpointItem struct {
+	URL      string
+	Depth    int
+	FoundVia string
+}
+
+// checkpointRecord is a crawled page or a failure, appended to the records
+// file as soon as the page is done
+type checkpointRecord struct {
+	Seq    int
+	Item   checkpointItem
+	Result *CrawlResult `json:",omitempty"`
+	Error  string       `json:",omitempty"`
+}
+
+// checkpoint persists a site crawl in a directory so it can be resumed
+type checkpoint struct {
+	dir string
+	// lock is the key of the crawl in runningCrawls
+	lock    string
+	records *os.File
+	// unsaved counts the records written since the state was last saved
+	unsaved int
+}
+
+// SetCheckpointDir enables resumable site crawls. The frontier and the
+// crawled pages are stored in a subdirectory per crawl ID. An empty
+// directory disables checkpointing.
+func (c *Crawler) SetCheckpointDir(dir string) {
+	c.checkpointDir = dir
+}
+
+// openCheckpoint opens the checkpoint of a crawl and loads its saved state
+// and records. The state is nil for a new crawl. The crawl is locked until
+// the checkpoint is closed; opening it again before fails with
+// ErrCrawlRunning.
+func openCheckpoint(baseDir, crawlID string) (cp *checkpoint, state *crawlState, records []checkpointRecord, err error) {
+	if !crawlIDPattern.MatchString(crawlID) {
+		return nil, nil, nil, fmt.Errorf("invalid crawl ID %q", crawlID)
+	}
+	dir := filepath.Join(baseDir, crawlID)
+	lock, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid checkpoint directory: %w", err)
+	}
+	if _, running := runningCrawls.LoadOrStore(lock, struct{}{}); running {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrCrawlRunning, crawlID)
+	}
+	defer func() {
+		if err != nil {
+			runningCrawls.Delete(lock)
+		}
+	}()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, checkpointStateFile))
+	switch {
+	case err == nil:
+		state = &crawlState{}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse checkpoint state: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, nil, nil, fmt.Errorf("failed to read checkpoint state: %w", err)
+	}
+
+	path := filepath.Join(dir, checkpointRecordsFile)
+	records, size, err := readCheckpointRecords(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Drop a partial last record so new records start on a fresh line
+	if err := os.Truncate(path, size); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, fmt.Errorf("failed to repair checkpoint records: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open checkpoint records: %w", err)
+	}
+	return &checkpoint{dir: dir, lock: lock, records: file}, state, records, nil
+}
+
+// readCheckpointRecords reads the records file and returns the records and
+// the size of the complete lines. A truncated last line, left by a process
+// that was killed while writing, is ignored.
+func readCheckpointRecords(path string) ([]checkpointRecord, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read checkpoint records: %w", err)
+	}
+	defer file.Close()
+
+	var records []checkpointRecord
+	var size int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read checkpoint records: %w", err)
+		}
+		var record checkpointRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, 0, fmt.Errorf("corrupt checkpoint record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+		size += int64(len(line))
+	}
+}
+
+// append writes a finished page to the records file
+func (cp *checkpoint) append(record checkpointRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint record: %w", err)
+	}
+	if _, err := cp.records.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint record: %w", err)
+	}
+	cp.unsaved++
+	return nil
+}
+
+// due reports whether enough pages finished to save the state again
+func (cp *checkpoint) due() bool {
+	return cp.unsaved >= checkpointInterval
+}
+
+// save replaces the saved state. The file is written next to the old one and
+// renamed, so a crash never leaves a partial state behind.
+func (cp *checkpoint) save(state *crawlState) error {
+	state.UpdatedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint state: %w", err)
+	}
+	path := filepath.Join(cp.dir, checkpointStateFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint state: %w", err)
+	}
+	if err := cp.records.Sync(); err != nil {
+		return fmt.Errorf("failed to sync checkpoint records: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write checkpoint state: %w", err)
+	}
+	cp.unsaved = 0
+	return nil
+}
+
+// close closes the records file and unlocks the crawl
+func (cp *checkpoint) close() error {
+	defer runningCrawls.Delete(cp.lock)
+	return cp.records.Close()
+}
+
+// snapshot captures the frontier, including the items in flight
+func (f *frontier) snapshot(inFlight map[int]frontierItem) ([]checkpointItem, []string) {
+	seqs := make([]int, 0, len(inFlight))
+	for seq := range inFlight {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+
+	queue := make([]checkpointItem, 0, len(inFlight)+len(f.queue))
+	for _, seq := range seqs {
+		item := inFlight[seq]
+		queue = append(queue, checkpointItem{URL: item.url, Depth: item.depth, FoundVia: item.foundVia})
+	}
+	for _, item := range f.queue {
+		queue = append(queue, checkpointItem{URL: item.url, Depth: item.depth, FoundVia: item.foundVia})
+	}
+	seen := make([]string, 0, len(f.seen))
+	for u := range f.seen {
+		seen = append(seen, u)
+	}
+	return queue, seen
+}
+
+// restore refills the frontier from a saved state, skipping URLs that were
+// already crawled
+func (f *frontier) restore(state *crawlState, done map[string]bool) {
+	for _, u := range state.Seen {
+		f.seen[u] = true
+	}
+	for _, item := range state.Queue {
+		if done[item.URL] {
+			continue
+		}
+		f.seen[item.URL] = true
+		f.enqueue(frontierItem{url: item.URL, depth: item.Depth, foundVia: item.FoundVia})
+	}
+}
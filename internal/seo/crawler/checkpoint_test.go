@@ -0,0 +1,247 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadCheckpointRecords(t *testing.T) {
+	const (
+		first  = `{"Seq":1,"Item":{"URL":"https://example.com/","Depth":0,"FoundVia":""}}` + "\n"
+		second = `{"Seq":2,"Item":{"URL":"https://example.com/a","Depth":1,"FoundVia":"https://example.com/"},"Error":"timeout"}` + "\n"
+	)
+
+	tests := []struct {
+		name     string
+		content  *string
+		wantSeqs []int
+		wantSize int64
+		wantErr  bool
+	}{
+		{name: "missing file", content: nil},
+		{name: "empty file", content: ptr("")},
+		{name: "complete records", content: ptr(first + second), wantSeqs: []int{1, 2}, wantSize: int64(len(first + second))},
+		{name: "truncated last record", content: ptr(first + `{"Seq":2,"Item":{"UR`), wantSeqs: []int{1}, wantSize: int64(len(first))},
+		{name: "last record without newline", content: ptr(first + second[:len(second)-1]), wantSeqs: []int{1}, wantSize: int64(len(first))},
+		{name: "corrupt record", content: ptr(first + "not json\n" + second), wantErr: true},
+		{name: "blank line", content: ptr(first + "\n" + second), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), checkpointRecordsFile)
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			records, size, err := readCheckpointRecords(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readCheckpointRecords() = %+v, want error", records)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCheckpointRecords() error = %v", err)
+			}
+			if size != tt.wantSize {
+				t.Errorf("size = %d, want %d", size, tt.wantSize)
+			}
+			if len(records) != len(tt.wantSeqs) {
+				t.Fatalf("records = %+v, want seqs %v", records, tt.wantSeqs)
+			}
+			for i, seq := range tt.wantSeqs {
+				if records[i].Seq != seq {
+					t.Errorf("records[%d].Seq = %d, want %d", i, records[i].Seq, seq)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenCheckpointRepairsRecords(t *testing.T) {
+	baseDir := t.TempDir()
+	cp, state, records, err := openCheckpoint(baseDir, "crawl-1")
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+	if state != nil || len(records) != 0 {
+		t.Fatalf("new checkpoint has state %+v and records %+v", state, records)
+	}
+	if err := cp.append(checkpointRecord{Seq: 1, Item: checkpointItem{URL: "https://example.com/"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crash in the middle of the next record
+	if _, err := cp.records.WriteString(`{"Seq":2,"It`); err != nil {
+		t.Fatal(err)
+	}
+	cp.close()
+
+	cp, _, records, err = openCheckpoint(baseDir, "crawl-1")
+	if err != nil {
+		t.Fatalf("reopening the checkpoint failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Item.URL != "https://example.com/" {
+		t.Fatalf("records = %+v, want the complete first record", records)
+	}
+	if err := cp.append(checkpointRecord{Seq: 2, Item: checkpointItem{URL: "https://example.com/a"}}); err != nil {
+		t.Fatal(err)
+	}
+	cp.close()
+
+	records, _, err = readCheckpointRecords(filepath.Join(baseDir, "crawl-1", checkpointRecordsFile))
+	if err != nil {
+		t.Fatalf("records after repair are corrupt: %v", err)
+	}
+	if len(records) != 2 || records[1].Seq != 2 {
+		t.Errorf("records = %+v, want seqs 1 and 2", records)
+	}
+}
+
+func TestOpenCheckpointInvalidID(t *testing.T) {
+	cp, _, _, err := openCheckpoint(t.TempDir(), strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatalf("openCheckpoint() with a 64 character ID failed: %v", err)
+	}
+	cp.close()
+	for _, crawlID := range []string{"", "../escape", "a/b", "crawl.1", strings.Repeat("a", 65)} {
+		if _, _, _, err := openCheckpoint(t.TempDir(), crawlID); err == nil {
+			t.Errorf("openCheckpoint(%q) succeeded, want error", crawlID)
+		}
+	}
+}
+
+func TestOpenCheckpointLock(t *testing.T) {
+	baseDir := t.TempDir()
+	cp, _, _, err := openCheckpoint(baseDir, "crawl-1")
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+	if _, _, _, err := openCheckpoint(baseDir, "crawl-1"); !errors.Is(err, ErrCrawlRunning) {
+		t.Errorf("opening a running crawl: error = %v, want %v", err, ErrCrawlRunning)
+	}
+	other, _, _, err := openCheckpoint(baseDir, "crawl-2")
+	if err != nil {
+		t.Errorf("another crawl is locked too: %v", err)
+	} else {
+		other.close()
+	}
+
+	cp.close()
+	cp, _, _, err = openCheckpoint(baseDir, "crawl-1")
+	if err != nil {
+		t.Fatalf("closing did not unlock the crawl: %v", err)
+	}
+	cp.close()
+}
+
+func TestCrawlDefinitionMismatch(t *testing.T) {
+	saved := crawlDefinition{
+		MaxDepth:    3,
+		UseSitemaps: true,
+		Scope:       CrawlScope{ExcludePaths: []string{"/tmp"}},
+		Normalize:   URLNormalizer{TrailingSlash: TrailingSlashStrip},
+	}
+
+	tests := []struct {
+		name   string
+		change func(d *crawlDefinition)
+		want   string
+	}{
+		{"same", func(d *crawlDefinition) {}, ""},
+		{"empty and nil paths are the same", func(d *crawlDefinition) { d.Scope.IncludePaths = []string{} }, ""},
+		{"max depth", func(d *crawlDefinition) { d.MaxDepth = 4 }, "max depth"},
+		{"sitemaps", func(d *crawlDefinition) { d.UseSitemaps = false }, "use sitemaps"},
+		{"excluded paths", func(d *crawlDefinition) { d.Scope.ExcludePaths = nil }, "scope"},
+		{"subdomains", func(d *crawlDefinition) { d.Scope.Subdomains = SubdomainsAll }, "scope"},
+		{"trailing slash", func(d *crawlDefinition) { d.Normalize.TrailingSlash = TrailingSlashAdd }, "URL normalization"},
+		{"no tracking parameters instead of the defaults", func(d *crawlDefinition) { d.Normalize.TrackingParams = []string{} }, "URL normalization"},
+		{"host aliases", func(d *crawlDefinition) {
+			d.Normalize.HostAliases = map[string]string{"www.example.com": "example.com"}
+		}, "URL normalization"},
+	}
+
+	for _, tt := range tests {
+		definition := saved
+		tt.change(&definition)
+		if got := definition.mismatch(saved); got != tt.want {
+			t.Errorf("%s: mismatch() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlSiteResume(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	pages := map[string]string{
+		"/":  testPage("Home", "/a", "/b", "/c"),
+		"/a": testPage("A", "/d"),
+		"/b": testPage("B"),
+		"/c": testPage("C"),
+		"/d": testPage("D"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler()
+	c.SetCheckpointDir(t.TempDir())
+	opts := SiteCrawlOptions{Concurrency: 1, MaxPages: 2, CrawlID: "resume"}
+
+	first, err := c.CrawlSite(context.Background(), srv.URL, opts)
+	if err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	if len(first.Pages) != 2 || first.CrawlID != "resume" {
+		t.Fatalf("first run crawled %v as %q, want 2 pages of crawl resume", pageURLs(first.Pages), first.CrawlID)
+	}
+
+	changed := opts
+	changed.MaxPages = 10
+	changed.MaxDepth = 1
+	if _, err := c.CrawlSite(context.Background(), srv.URL, changed); !errors.Is(err, ErrCrawlMismatch) {
+		t.Errorf("resume with another max depth: error = %v, want %v", err, ErrCrawlMismatch)
+	}
+	if _, err := c.CrawlSite(context.Background(), srv.URL+"/b", SiteCrawlOptions{MaxPages: 10, CrawlID: "resume"}); !errors.Is(err, ErrCrawlMismatch) {
+		t.Errorf("resume with another start URL: error = %v, want %v", err, ErrCrawlMismatch)
+	}
+
+	opts.MaxPages = 10
+	second, err := c.CrawlSite(context.Background(), srv.URL, opts)
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	want := []string{srv.URL + "/", srv.URL + "/a", srv.URL + "/b", srv.URL + "/c", srv.URL + "/d"}
+	got := pageURLs(second.Pages)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Pages = %v, want %v", got, want)
+	}
+	for path, n := range requests {
+		if n != 1 {
+			t.Errorf("%s was requested %d times, want once", path, n)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
@@ -14,48 +15,119 @@ import (
 
 // CrawlResult represents the result of crawling a single page
 type CrawlResult struct {
-	URL              string
-	StatusCode       int
-	Title            string
-	MetaDescription  string
-	H1Tags           []string
-	H2Tags           []string
-	Links            []string
-	Images           []Image
-	WordCount        int
-	LoadTimeMs       int64
-	MobileFriendly   bool
-	HasHTTPS         bool
-	CanonicalURL     string
-	Errors           []string
-	Headers          map[string]string
-	ResponseSize     int64
+	URL                 string
+	FinalURL            string
+	StatusCode          int
+	Title               string
+	MetaDescription     string
+	H1Tags              []string
+	H2Tags              []string
+	Headings            []Heading
+	Links               []string
+	LinkDetails         []Link
+	Images              []Image
+	WordCount           int
+	Content             ContentFingerprint
+	Soft404             *Soft404
+	LoadTimeMs          int64
+	Transfer            Transfer
+	Assets              []Asset
+	Weight              PageWeight
+	MobileFriendly      bool
+	Viewport            Viewport
+	SmallFonts          []StyleFinding
+	FixedWidths         []StyleFinding
+	MobileParity        *MobileParity
+	HasHTTPS            bool
+	CanonicalURL        string
+	Language            string
+	Hreflang            []HreflangLink
+	RobotsDirectives    RobotsDirectives
+	BotRobotsDirectives map[string]RobotsDirectives
+	Indexability        Indexability
+	StructuredData      []StructuredDataItem
+	OpenGraph           OpenGraph
+	TwitterCard         TwitterCard
+	Errors              []string
+	Headers             http.Header
+	TLS                 *TLSCertificate
+	Cookies             []Cookie
+	MixedContent        []MixedContent
+	ResponseSize        int64
+	BlockedByRobotsTxt  bool
+	RedirectChain       []RedirectHop
+	Depth               int
+	FoundVia            string
+	// BodyHash is the SHA-256 of the decoded response body
+	BodyHash string
+	// Change is set when the page was crawled again, see RecrawlPage
+	Change *PageChange
+
+	// previous is the result of the last crawl, used for conditional requests
+	previous *CrawlResult
 }
 
-// Image represents an image found on the page
+// Image represents an image found on the page. Width and Height are the
+// declared attributes, the other size fields are set once the image was fetched.
 type Image struct {
-	Src    string
-	Alt    string
-	Title  string
-	Width  int
-	Height int
+	Src       string
+	Alt       string
+	Title     string
+	Width     int
+	Height    int
+	Srcset    string
+	Sizes     string
+	Lazy      bool
+	InPicture bool
+	Sources   []ImageSource
+
+	Checked      bool
+	StatusCode   int
+	ContentType  string
+	Size         int64
+	ActualWidth  int
+	ActualHeight int
 }
 
 // Crawler handles website crawling
 type Crawler struct {
-	userAgent          string
-	timeout            time.Duration
-	maxDepth           int
-	respectRobotsTxt   bool
-	crawlDelay         time.Duration
-	maxConcurrent      int
-	client             *http.Client
-	visitedURLs        sync.Map
-	lastRequestTime    sync.Map
+	userAgent        string
+	timeout          time.Duration
+	maxDepth         int
+	respectRobotsTxt bool
+	crawlDelay       time.Duration
+	maxConcurrent    int
+	acceptLanguage   string
+	mobileParity     bool
+	checkAssets      bool
+	soft404Probe     bool
+	checkpointDir    string
+	redactCookies    bool
+	egress           Egress
+	client           *http.Client
+	pageClient       *http.Client
+	visitedURLs      sync.Map
+	maxRetries       int
+	rateLimiter      *hostRateLimiter
+	robots           robotsCache
+}
+
+// crawlCache holds the fetch results that the pages of one crawl share,
+// such as stylesheets and scripts used on every page. Each crawl starts with
+// an empty cache, so it measures the current state of a site.
+type crawlCache struct {
+	assets         assetCache
+	socialImages   socialImageCache
+	linkChecks     linkCheckCache
+	notFoundProbes notFoundProbeCache
 }
 
 // NewCrawler creates a new crawler instance
 func NewCrawler(userAgent string, timeout time.Duration, maxDepth int) *Crawler {
+	// newTransport only fails on an unknown or invalid proxy, an invalid
+	// source IP or an unreadable CA file, none of which the empty egress sets
+	transport, _ := Egress{}.newTransport("", nil, false)
+
 	return &Crawler{
 		userAgent:        userAgent,
 		timeout:          timeout,
@@ -63,8 +135,14 @@ func NewCrawler(userAgent string, timeout time.Duration, maxDepth int) *Crawler
 		respectRobotsTxt: true,
 		crawlDelay:       1 * time.Second,
 		maxConcurrent:    5,
+		maxRetries:       defaultMaxRetries,
+		rateLimiter:      &hostRateLimiter{},
+		acceptLanguage:   "de-DE,de;q=0.9,en;q=0.8",
+		checkAssets:      true,
+		soft404Probe:     true,
 		client: &http.Client{
-			Timeout: timeout,
+			Transport: transport,
+			Timeout:   timeout,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				if len(via) >= 10 {
 					return fmt.Errorf("too many redirects")
@@ -72,70 +150,164 @@ func NewCrawler(userAgent string, timeout time.Duration, maxDepth int) *Crawler
 				return nil
 			},
 		},
+		// Pages are fetched without automatic redirects so every hop can be recorded
+		pageClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// SetRespectRobotsTxt controls whether robots.txt is fetched and honored
+func (c *Crawler) SetRespectRobotsTxt(respect bool) {
+	c.respectRobotsTxt = respect
+}
+
+// SetMaxConcurrent sets the default number of parallel workers of a site crawl
+func (c *Crawler) SetMaxConcurrent(n int) {
+	if n > 0 {
+		c.maxConcurrent = n
+	}
+}
+
+// SetAcceptLanguage sets the Accept-Language header sent with page requests.
+// An empty value sends no header, which is how search engine bots crawl.
+func (c *Crawler) SetAcceptLanguage(acceptLanguage string) {
+	c.acceptLanguage = acceptLanguage
+}
+
+// SetMobileParity enables a second request per page with a smartphone user
+// agent to compare the mobile and desktop content
+func (c *Crawler) SetMobileParity(enabled bool) {
+	c.mobileParity = enabled
+}
+
+// SetCrawlDelay sets the minimum delay between two requests to the same host
+func (c *Crawler) SetCrawlDelay(delay time.Duration) {
+	if delay >= 0 {
+		c.crawlDelay = delay
 	}
 }
 
-// CrawlPage crawls a single page and returns the result
+// CrawlPage crawls a single page and returns the result. Redirects are
+// followed and recorded in RedirectChain. If robots.txt disallows the URL or
+// a redirect target, no further request is made and the returned result is
+// marked as blocked.
 func (c *Crawler) CrawlPage(ctx context.Context, urlStr string) (*CrawlResult, error) {
+	return c.crawlPage(ctx, &crawlCache{}, urlStr, nil)
+}
+
+// crawlPage crawls a page, conditionally if there is a previous result.
+// cache is shared with the other pages of the crawl.
+func (c *Crawler) crawlPage(ctx context.Context, cache *crawlCache, urlStr string, previous *CrawlResult) (*CrawlResult, error) {
 	// Validate URL
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	// Enforce crawl delay
-	c.enforceCrawlDelay(parsedURL.Host)
+	result := &CrawlResult{
+		URL:      urlStr,
+		FinalURL: urlStr,
+		HasHTTPS: parsedURL.Scheme == "https",
+		Headers:  make(http.Header),
+		Errors:   []string{},
+		previous: previous,
+	}
 
 	// Start timing
 	startTime := time.Now()
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	// Execute request, following redirects
+	resp, err := c.fetchPage(ctx, parsedURL, result, c.userAgent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	if resp == nil {
+		// Blocked by robots.txt or the redirect chain did not end in a page
+		result.Indexability = result.computeIndexability()
+		result.compareUnfetched(previous)
+		result.previous = nil
+		return result, nil
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	if previous != nil && resp.StatusCode == http.StatusNotModified {
+		result.LoadTimeMs = time.Since(startTime).Milliseconds()
+		c.visitedURLs.Store(urlStr, true)
+		return c.reusePage(ctx, cache, result, previous, ChangeNotModified), nil
+	}
 
-	// Execute request
-	resp, err := c.client.Do(req)
+	body, err := readPageBody(resp, result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
+	// Load time covers redirects, the response and the download, not parsing
 	loadTime := time.Since(startTime).Milliseconds()
+	finalURL := resp.Request.URL
+	result.BodyHash = bodyHash(body)
+
+	// The same body parses to the same result
+	if previous != nil && result.BodyHash == previous.BodyHash && resp.StatusCode == previous.StatusCode {
+		result.LoadTimeMs = loadTime
+		result.Headers = c.responseHeaders(resp)
+		result.parseTLS(resp.TLS)
+		result.parseCookies(resp)
+		c.visitedURLs.Store(urlStr, true)
+		return c.reusePage(ctx, cache, result, previous, ChangeUnchanged), nil
+	}
 
 	// Parse HTML
-	doc, err := html.Parse(resp.Body)
+	doc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	// Extract data
-	result := &CrawlResult{
-		URL:          urlStr,
-		StatusCode:   resp.StatusCode,
-		LoadTimeMs:   loadTime,
-		HasHTTPS:     parsedURL.Scheme == "https",
-		Headers:      make(map[string]string),
-		Errors:       []string{},
-	}
+	result.FinalURL = finalURL.String()
+	result.StatusCode = resp.StatusCode
+	result.LoadTimeMs = loadTime
+	result.HasHTTPS = finalURL.Scheme == "https"
 
-	// Extract headers
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			result.Headers[key] = values[0]
-		}
-	}
+	// Extract headers, keeping repeated ones such as Set-Cookie and Link
+	result.Headers = c.responseHeaders(resp)
+	result.parseTLS(resp.TLS)
+	result.parseCookies(resp)
+
+	result.parseXRobotsTag(resp.Header)
+	result.parseLinkHeader(resp.Header, finalURL)
 
 	// Parse document
-	c.parseNode(doc, result, parsedURL)
+	c.parseNode(doc, result, finalURL)
+	result.Content = c.fingerprintContent(doc)
+	result.StructuredData = c.extractStructuredData(doc, finalURL)
+	if result.HasHTTPS {
+		c.findMixedContent(doc, result, finalURL)
+	}
+	c.checkSocialImages(ctx, &cache.socialImages, result)
+	result.Assets = c.collectAssets(doc, finalURL)
+	if c.checkAssets {
+		c.fetchAssets(ctx, cache, result)
+		result.applyImageChecks()
+	}
+	result.Weight = result.computeWeight()
+	result.Indexability = result.computeIndexability()
+
+	if result.StatusCode == http.StatusOK {
+		c.checkSoft404(ctx, &cache.notFoundProbes, doc, result, finalURL)
+	}
 
-	// Check mobile-friendly (simplified check)
 	result.MobileFriendly = c.checkMobileFriendly(result)
+	if c.mobileParity && result.StatusCode == http.StatusOK {
+		c.checkMobileParity(ctx, result)
+	}
+
+	result.compareCrawls(previous)
+	result.previous = nil
 
 	// Mark as visited
 	c.visitedURLs.Store(urlStr, true)
@@ -143,47 +315,64 @@ func (c *Crawler) CrawlPage(ctx context.Context, urlStr string) (*CrawlResult, e
 	return result, nil
 }
 
+// newPageRequest creates a GET request for an HTML page
+func (c *Crawler) newPageRequest(ctx context.Context, urlStr, userAgent string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	// Decompressed by readPageBody, so the transferred size can be measured
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if c.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.acceptLanguage)
+	}
+
+	return req, nil
+}
+
 // parseNode recursively parses HTML nodes
 func (c *Crawler) parseNode(n *html.Node, result *CrawlResult, baseURL *url.URL) {
 	if n.Type == html.ElementNode {
+		if style := c.getAttr(n, "style"); style != "" {
+			result.checkInlineStyle(n.Data, style)
+		}
 		switch n.Data {
 		case "title":
 			if n.FirstChild != nil {
 				result.Title = strings.TrimSpace(n.FirstChild.Data)
 			}
 		case "meta":
-			c.parseMeta(n, result)
+			c.parseMeta(n, result, baseURL)
 		case "h1":
+			c.addHeading(n, result)
 			text := c.extractText(n)
 			if text != "" {
 				result.H1Tags = append(result.H1Tags, text)
 			}
 		case "h2":
+			c.addHeading(n, result)
 			text := c.extractText(n)
 			if text != "" {
 				result.H2Tags = append(result.H2Tags, text)
 			}
+		case "h3", "h4", "h5", "h6":
+			c.addHeading(n, result)
 		case "a":
-			href := c.getAttr(n, "href")
-			if href != "" {
-				if absURL := c.makeAbsolute(href, baseURL); absURL != "" {
-					result.Links = append(result.Links, absURL)
-				}
-			}
+			c.addLink(n, result, baseURL)
 		case "img":
-			img := Image{
-				Src:   c.getAttr(n, "src"),
-				Alt:   c.getAttr(n, "alt"),
-				Title: c.getAttr(n, "title"),
-			}
-			if img.Src != "" {
-				img.Src = c.makeAbsolute(img.Src, baseURL)
-				result.Images = append(result.Images, img)
-			}
+			c.addImage(n, result, baseURL)
+		case "html":
+			result.Language = strings.TrimSpace(c.getAttr(n, "lang"))
 		case "link":
 			rel := c.getAttr(n, "rel")
 			if rel == "canonical" {
-				result.CanonicalURL = c.getAttr(n, "href")
+				result.CanonicalURL = c.makeAbsolute(c.getAttr(n, "href"), baseURL)
+			}
+			if hreflang := c.getAttr(n, "hreflang"); hreflang != "" && hasRel(rel, "alternate") {
+				result.addHreflang(hreflang, c.makeAbsolute(strings.TrimSpace(c.getAttr(n, "href")), baseURL), HreflangSourceHTML)
 			}
 		}
 	}
@@ -202,7 +391,7 @@ func (c *Crawler) parseNode(n *html.Node, result *CrawlResult, baseURL *url.URL)
 }
 
 // parseMeta extracts meta tag information
-func (c *Crawler) parseMeta(n *html.Node, result *CrawlResult) {
+func (c *Crawler) parseMeta(n *html.Node, result *CrawlResult, baseURL *url.URL) {
 	name := c.getAttr(n, "name")
 	property := c.getAttr(n, "property")
 	content := c.getAttr(n, "content")
@@ -212,6 +401,27 @@ func (c *Crawler) parseMeta(n *html.Node, result *CrawlResult) {
 			result.MetaDescription = content
 		}
 	}
+
+	if name != "" {
+		result.parseMetaRobots(name, content)
+	}
+
+	if strings.EqualFold(name, "viewport") {
+		result.Viewport = parseViewport(content)
+	}
+
+	// Open Graph and Twitter Card tags, which sites declare with either attribute
+	key := property
+	if key == "" {
+		key = name
+	}
+	if lower := strings.ToLower(key); strings.HasPrefix(lower, "og:") || strings.HasPrefix(lower, "twitter:") {
+		absContent := ""
+		if content != "" {
+			absContent = c.makeAbsolute(strings.TrimSpace(content), baseURL)
+		}
+		result.parseSocialMeta(key, content, absContent)
+	}
 }
 
 // extractText extracts all text content from a node
@@ -254,77 +464,8 @@ func (c *Crawler) makeAbsolute(href string, baseURL *url.URL) string {
 	return baseURL.ResolveReference(parsed).String()
 }
 
-// checkMobileFriendly performs a basic mobile-friendly check
-func (c *Crawler) checkMobileFriendly(result *CrawlResult) bool {
-	// Check for viewport meta tag in headers
-	viewport := result.Headers["viewport"]
-	if viewport != "" {
-		return strings.Contains(strings.ToLower(viewport), "width=device-width")
-	}
-	return false
-}
-
-// enforceCrawlDelay ensures we respect crawl delay between requests to the same host
-func (c *Crawler) enforceCrawlDelay(host string) {
-	if lastTime, ok := c.lastRequestTime.Load(host); ok {
-		if last, ok := lastTime.(time.Time); ok {
-			elapsed := time.Since(last)
-			if elapsed < c.crawlDelay {
-				time.Sleep(c.crawlDelay - elapsed)
-			}
-		}
-	}
-	c.lastRequestTime.Store(host, time.Now())
-}
-
 // IsVisited checks if a URL has been visited
 func (c *Crawler) IsVisited(urlStr string) bool {
 	_, visited := c.visitedURLs.Load(urlStr)
 	return visited
 }
-
-// CrawlSite crawls an entire site (multiple pages) - simplified version
-func (c *Crawler) CrawlSite(ctx context.Context, startURL string, maxPages int) ([]*CrawlResult, error) {
-	results := make([]*CrawlResult, 0)
-	queue := []string{startURL}
-	visited := make(map[string]bool)
-
-	baseURL, err := url.Parse(startURL)
-	if err != nil {
-		return nil, err
-	}
-
-	for len(queue) > 0 && len(results) < maxPages {
-		current := queue[0]
-		queue = queue[1:]
-
-		if visited[current] {
-			continue
-		}
-
-		result, err := c.CrawlPage(ctx, current)
-		if err != nil {
-			continue
-		}
-
-		results = append(results, result)
-		visited[current] = true
-
-		// Add internal links to queue
-		if len(results) < maxPages {
-			for _, link := range result.Links {
-				linkURL, err := url.Parse(link)
-				if err != nil {
-					continue
-				}
-
-				// Only follow internal links
-				if linkURL.Host == baseURL.Host && !visited[link] {
-					queue = append(queue, link)
-				}
-			}
-		}
-	}
-
-	return results, nil
-}
@@ -0,0 +1,390 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// defaultDuplicateThreshold is the SimHash similarity above which two
+	// pages count as near-duplicates
+	defaultDuplicateThreshold = 0.9
+	// minFingerprintWords is the minimum main content length for the
+	// near-duplicate comparison, short texts are too similar by chance
+	minFingerprintWords = 50
+	// shingleSize is the number of words per shingle
+	shingleSize = 4
+)
+
+// boilerplateElements never belong to the main content of a page
+var boilerplateElements = map[string]bool{
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"aside":    true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"form":     true,
+	"iframe":   true,
+	"select":   true,
+	"button":   true,
+}
+
+// boilerplateNames are id and class name parts of navigation, sidebars and
+// banners
+var boilerplateNames = map[string]bool{
+	"nav":         true,
+	"navbar":      true,
+	"navigation":  true,
+	"menu":        true,
+	"sidebar":     true,
+	"footer":      true,
+	"header":      true,
+	"breadcrumb":  true,
+	"breadcrumbs": true,
+	"cookie":      true,
+	"cookies":     true,
+	"banner":      true,
+	"newsletter":  true,
+	"share":       true,
+	"social":      true,
+}
+
+// ContentFingerprint identifies the main content of a page, without
+// navigation, header, footer and sidebars
+type ContentFingerprint struct {
+	Words int
+	// Hash is the SHA-256 of the normalized main content, equal for exact duplicates
+	Hash string
+	// SimHash is a 64-bit fingerprint of the word shingles, similar texts
+	// differ in few bits
+	SimHash uint64
+}
+
+// Similarity returns the share of equal SimHash bits, 1 for identical and
+// about 0.5 for unrelated texts
+func (f ContentFingerprint) Similarity(other ContentFingerprint) float64 {
+	return 1 - float64(bits.OnesCount64(f.SimHash^other.SimHash))/64
+}
+
+// DuplicateReport lists pages with duplicate content, titles, meta
+// descriptions and H1s across a site crawl
+type DuplicateReport struct {
+	Threshold             float64
+	ExactDuplicates       []DuplicateGroup
+	NearDuplicates        []NearDuplicateGroup
+	DuplicateTitles       []DuplicateGroup
+	DuplicateDescriptions []DuplicateGroup
+	DuplicateH1s          []DuplicateGroup
+}
+
+// DuplicateGroup is a set of pages sharing the same value
+type DuplicateGroup struct {
+	Value string
+	URLs  []string
+}
+
+// NearDuplicateGroup is a set of pages whose main content is nearly the same.
+// Similarity is the lowest similarity of the pairs that joined the group.
+// Exact duplicates appear together in the group of their content.
+type NearDuplicateGroup struct {
+	URLs       []string
+	Similarity float64
+}
+
+// fingerprintContent extracts the main content of a page and fingerprints it
+func (c *Crawler) fingerprintContent(doc *html.Node) ContentFingerprint {
+	words := contentWords(c.mainContent(doc))
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return ContentFingerprint{
+		Words:   len(words),
+		Hash:    hex.EncodeToString(sum[:]),
+		SimHash: simHash(words),
+	}
+}
+
+// mainContent returns the text of the main content of a page: the <main>
+// element or, if there is none, a single <article> or the <body>, without
+// boilerplate such as navigation, sidebars and footers
+func (c *Crawler) mainContent(doc *html.Node) string {
+	var main, body *html.Node
+	var articles []*html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case main == nil && (n.Data == "main" || strings.EqualFold(c.getAttr(n, "role"), "main")):
+				main = n
+			case n.Data == "article":
+				articles = append(articles, n)
+			case n.Data == "body":
+				body = n
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			find(child)
+		}
+	}
+	find(doc)
+
+	root := body
+	switch {
+	case main != nil:
+		root = main
+	case len(articles) == 1:
+		root = articles[0]
+	}
+	if root == nil {
+		root = doc
+	}
+
+	var buf strings.Builder
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			buf.WriteByte(' ')
+			return
+		case html.ElementNode:
+			if n != root && c.isBoilerplate(n) {
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			extract(child)
+		}
+	}
+	extract(root)
+	return buf.String()
+}
+
+// isBoilerplate reports whether an element is navigation, a sidebar, a
+// banner or another part of the page template
+func (c *Crawler) isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.Data] {
+		return true
+	}
+	switch strings.ToLower(c.getAttr(n, "role")) {
+	case "navigation", "banner", "contentinfo", "complementary":
+		return true
+	}
+	names := strings.FieldsFunc(strings.ToLower(c.getAttr(n, "id")+" "+c.getAttr(n, "class")), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	})
+	for _, name := range names {
+		if boilerplateNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// contentWords splits text into lowercase words
+func contentWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// simHash computes the SimHash of the word shingles of a text
+func simHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	size := shingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		sum := mix64(h.Sum64())
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// mix64 spreads the bits of a hash (the splitmix64 finalizer), FNV alone
+// leaves the high bits of similar inputs correlated
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// auditDuplicates compares the indexable pages of a site crawl. Pages that
+// redirect, fail, are noindex or canonicalize to another URL are skipped,
+// since they already tell search engines which version to use. Soft 404s
+// are skipped as well, they share the error template.
+func auditDuplicates(pages []*CrawlResult, threshold float64) *DuplicateReport {
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultDuplicateThreshold
+	}
+	report := &DuplicateReport{Threshold: threshold}
+
+	var indexable []*CrawlResult
+	for _, page := range pages {
+		if page.StatusCode == http.StatusOK && len(page.RedirectChain) == 0 && page.Indexability.Indexable &&
+			(page.Soft404 == nil || !page.Soft404.Detected) {
+			indexable = append(indexable, page)
+		}
+	}
+
+	report.DuplicateTitles = groupDuplicates(indexable, func(p *CrawlResult) string { return p.Title })
+	report.DuplicateDescriptions = groupDuplicates(indexable, func(p *CrawlResult) string { return p.MetaDescription })
+	report.DuplicateH1s = groupDuplicates(indexable, func(p *CrawlResult) string {
+		if len(p.H1Tags) == 0 {
+			return ""
+		}
+		return p.H1Tags[0]
+	})
+
+	// Exact duplicates share the content hash
+	byHash := make(map[string][]*CrawlResult)
+	var hashes []string
+	for _, page := range indexable {
+		if page.Content.Words == 0 {
+			continue
+		}
+		if _, ok := byHash[page.Content.Hash]; !ok {
+			hashes = append(hashes, page.Content.Hash)
+		}
+		byHash[page.Content.Hash] = append(byHash[page.Content.Hash], page)
+	}
+	for _, hash := range hashes {
+		if group := byHash[hash]; len(group) > 1 {
+			report.ExactDuplicates = append(report.ExactDuplicates, DuplicateGroup{
+				Value: hash,
+				URLs:  pageURLs(group),
+			})
+		}
+	}
+
+	// Near-duplicates are compared once per distinct content and grouped by
+	// single linkage
+	var distinct []*CrawlResult
+	for _, hash := range hashes {
+		if page := byHash[hash][0]; page.Content.Words >= minFingerprintWords {
+			distinct = append(distinct, page)
+		}
+	}
+	parent := make([]int, len(distinct))
+	lowest := make([]float64, len(distinct))
+	for i := range parent {
+		parent[i] = i
+		lowest[i] = 1
+	}
+	var root func(int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i := range distinct {
+		for j := i + 1; j < len(distinct); j++ {
+			similarity := distinct[i].Content.Similarity(distinct[j].Content)
+			if similarity < threshold {
+				continue
+			}
+			ri, rj := root(i), root(j)
+			if ri != rj {
+				parent[rj] = ri
+				lowest[ri] = min(lowest[ri], lowest[rj])
+			}
+			lowest[ri] = min(lowest[ri], similarity)
+		}
+	}
+	groups := make(map[int][]*CrawlResult)
+	var roots []int
+	for i, page := range distinct {
+		r := root(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], byHash[page.Content.Hash]...)
+	}
+	for _, r := range roots {
+		if len(groups[r]) > 1 {
+			report.NearDuplicates = append(report.NearDuplicates, NearDuplicateGroup{
+				URLs:       pageURLs(groups[r]),
+				Similarity: lowest[r],
+			})
+		}
+	}
+
+	return report
+}
+
+// groupDuplicates groups pages by a case- and whitespace-insensitive value.
+// Empty values are ignored, missing titles and descriptions are reported
+// per page.
+func groupDuplicates(pages []*CrawlResult, value func(*CrawlResult) string) []DuplicateGroup {
+	byValue := make(map[string]*DuplicateGroup)
+	var keys []string
+	for _, page := range pages {
+		v := strings.Join(strings.Fields(value(page)), " ")
+		if v == "" {
+			continue
+		}
+		key := strings.ToLower(v)
+		group, ok := byValue[key]
+		if !ok {
+			group = &DuplicateGroup{Value: v}
+			byValue[key] = group
+			keys = append(keys, key)
+		}
+		group.URLs = append(group.URLs, page.URL)
+	}
+
+	var groups []DuplicateGroup
+	for _, key := range keys {
+		if group := byValue[key]; len(group.URLs) > 1 {
+			groups = append(groups, *group)
+		}
+	}
+	// Largest groups first, they point to template problems
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].URLs) > len(groups[j].URLs)
+	})
+	return groups
+}
+
+// pageURLs returns the URLs of pages
+func pageURLs(pages []*CrawlResult) []string {
+	urls := make([]string, len(pages))
+	for i, page := range pages {
+		urls[i] = page.URL
+	}
+	return urls
+}
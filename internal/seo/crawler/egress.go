@@ -0,0 +1,170 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// defaultMaxIdleConnsPerHost keeps enough connections open for the
+	// parallel workers of a site crawl
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Egress configures how the crawler connects to sites: through which proxy,
+// from which address and with which TLS trust. The page and check requests
+// of a crawler share one transport and thus one connection pool.
+type Egress struct {
+	// Proxy is an http, https, socks5 or socks5h proxy URL. Empty uses the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+	// Proxies are named proxy URLs that a crawl can select, see Access.Proxy
+	Proxies map[string]string
+	// DNSServer is the host:port of a DNS server used instead of the system
+	// resolver
+	DNSServer string
+	// SourceIP is the local address outgoing connections are bound to, for
+	// sites that only allow known egress addresses
+	SourceIP string
+	// MaxConnsPerHost limits the connections per host, 0 means no limit
+	MaxConnsPerHost     int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// CAFile is a PEM bundle of certificate authorities trusted in addition
+	// to the system ones
+	CAFile string
+	// InsecureSkipVerify disables certificate verification for all hosts
+	InsecureSkipVerify bool
+}
+
+// SetEgress replaces the transport of the crawler. Open idle connections of
+// the previous transport are closed.
+func (c *Crawler) SetEgress(egress Egress) error {
+	transport, err := egress.newTransport("", nil, false)
+	if err != nil {
+		return err
+	}
+	if previous, ok := c.client.Transport.(*http.Transport); ok {
+		previous.CloseIdleConnections()
+	}
+	c.egress = egress
+	c.client.Transport = transport
+	c.pageClient.Transport = transport
+	return nil
+}
+
+// newTransport builds a transport for the egress. proxyName selects one of
+// the named proxies, resolve overrides the addresses of hosts and insecure
+// disables certificate verification.
+func (e Egress) newTransport(proxyName string, resolve map[string]string, insecure bool) (*http.Transport, error) {
+	proxy, err := e.proxyFunc(proxyName)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := e.tlsConfig(insecure)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if e.SourceIP != "" {
+		ip := net.ParseIP(e.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", e.SourceIP)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	if e.DNSServer != "" {
+		server := e.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		// DNS queries may use UDP, so they are not bound to the source IP
+		dnsDialer := &net.Dialer{Timeout: 5 * time.Second}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dnsDialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	maxIdle := e.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConnsPerHost
+	}
+	idleTimeout := e.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, resolveAddr(resolve, addr))
+		},
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdle,
+		MaxConnsPerHost:       e.MaxConnsPerHost,
+		IdleConnTimeout:       idleTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// proxyFunc returns the proxy selection of the transport. Proxy URLs may
+// contain credentials, so they are left out of errors.
+func (e Egress) proxyFunc(name string) (func(*http.Request) (*url.URL, error), error) {
+	raw := e.Proxy
+	if name != "" {
+		var ok bool
+		if raw, ok = e.Proxies[name]; !ok {
+			return nil, fmt.Errorf("unknown proxy %q", name)
+		}
+	}
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// tlsConfig returns the TLS settings of the transport
+func (e Egress) tlsConfig(insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: e.InsecureSkipVerify || insecure}
+	if e.CAFile != "" {
+		pem, err := os.ReadFile(e.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", e.CAFile)
+		}
+		config.RootCAs = roots
+	}
+
+	return config, nil
+}
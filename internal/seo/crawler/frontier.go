@@ -0,0 +1,74 @@
+package crawler
+
+// URL discovery sources
+const (
+	FoundViaStart   = "start"
+	FoundViaLink    = "link"
+	FoundViaSitemap = "sitemap"
+)
+
+// frontierItem is a URL waiting to be crawled
+type frontierItem struct {
+	url      string
+	depth    int
+	foundVia string
+}
+
+// frontier is the FIFO queue of a site crawl. URLs are expected in
+// normalized form and are queued at most once, at the smallest depth they
+// were discovered at.
+type frontier struct {
+	queue []frontierItem
+	seen  map[string]bool
+}
+
+// newFrontier creates an empty frontier
+func newFrontier() *frontier {
+	return &frontier{
+		seen: make(map[string]bool),
+	}
+}
+
+// markSeen records a URL and reports whether it was new
+func (f *frontier) markSeen(urlStr string) bool {
+	if f.seen[urlStr] {
+		return false
+	}
+	f.seen[urlStr] = true
+	return true
+}
+
+// push queues a URL unless it was seen before
+func (f *frontier) push(urlStr string, depth int, foundVia string) bool {
+	if !f.markSeen(urlStr) {
+		return false
+	}
+	f.enqueue(frontierItem{url: urlStr, depth: depth, foundVia: foundVia})
+	return true
+}
+
+// enqueue appends an item without checking the seen set
+func (f *frontier) enqueue(item frontierItem) {
+	f.queue = append(f.queue, item)
+}
+
+// peek returns the next URL without removing it
+func (f *frontier) peek() (frontierItem, bool) {
+	if len(f.queue) == 0 {
+		return frontierItem{}, false
+	}
+	return f.queue[0], true
+}
+
+// pop removes the next URL
+func (f *frontier) pop() {
+	if len(f.queue) > 0 {
+		f.queue[0] = frontierItem{}
+		f.queue = f.queue[1:]
+	}
+}
+
+// len returns the number of queued URLs
+func (f *frontier) len() int {
+	return len(f.queue)
+}
@@ -0,0 +1,405 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// pageRankDamping is the probability of following a link instead of
+	// jumping to a random page
+	pageRankDamping = 0.85
+	// pageRankIterations bounds the power iteration
+	pageRankIterations = 100
+	// pageRankTolerance ends the iteration once the ranks are stable
+	pageRankTolerance = 1e-9
+	// maxEdgeAnchors is the number of distinct anchor texts kept per edge
+	maxEdgeAnchors = 5
+)
+
+// LinkGraph is the internal link graph of a site crawl. Nodes are the
+// canonical versions of the crawled pages; links to redirecting or
+// canonicalized URLs count for their target.
+type LinkGraph struct {
+	Home  string
+	Nodes []GraphNode
+	Edges []GraphEdge
+	// Orphans are sitemap URLs that no crawled page links to. They are only
+	// determined when the crawl finished; a crawl stopped by its page limit
+	// lists the unlinked sitemap URLs it did not crawl in NotReached, since
+	// pages it did not crawl may link to them.
+	Orphans    []string
+	NotReached []string
+	// DeadEnds are pages without internal outlinks
+	DeadEnds []string
+}
+
+// GraphNode is a page of the link graph
+type GraphNode struct {
+	URL        string
+	Title      string
+	StatusCode int
+	// Depth is the number of clicks from the homepage, -1 if unreachable
+	Depth     int
+	Inlinks   int
+	Outlinks  int
+	PageRank  float64
+	InSitemap bool
+	Orphan    bool
+	DeadEnd   bool
+}
+
+// GraphEdge is the set of links from one page to another
+type GraphEdge struct {
+	Source  string
+	Target  string
+	Anchors []string
+	// Count is the number of links, Followed the number without nofollow,
+	// sponsored or ugc
+	Count    int
+	Followed int
+}
+
+// buildLinkGraph builds the internal link graph of the crawled pages;
+// finished is set if the crawl was not stopped before its frontier was empty
+func buildLinkGraph(pages []*CrawlResult, sitemapURLs []SitemapURL, startURL string, normalizer *URLNormalizer, finished bool) *LinkGraph {
+	normalize := func(u string) string {
+		if n, err := normalizer.Normalize(u); err == nil {
+			return n
+		}
+		return u
+	}
+
+	crawled := make(map[string]*CrawlResult, len(pages))
+	for _, page := range pages {
+		crawled[normalize(page.URL)] = page
+	}
+
+	// resolve maps a URL to the canonical page it stands for, following
+	// redirects and canonical tags of crawled pages
+	resolve := func(u string) string {
+		key := normalize(u)
+		for i := 0; i < 5; i++ {
+			page, ok := crawled[key]
+			if !ok {
+				break
+			}
+			next := key
+			switch {
+			case len(page.RedirectChain) > 0:
+				next = normalize(page.FinalURL)
+			case page.Indexability.Reason == IndexabilityCanonicalized && page.CanonicalURL != "":
+				next = normalize(page.CanonicalURL)
+			}
+			if next == key {
+				break
+			}
+			key = next
+		}
+		return key
+	}
+
+	graph := &LinkGraph{Home: resolve(startURL)}
+	index := make(map[string]int)
+	nodePages := make(map[string]*CrawlResult)
+	for _, page := range pages {
+		key := resolve(page.URL)
+		if _, ok := index[key]; ok {
+			continue
+		}
+		// The result of a redirected URL describes its target
+		target, ok := crawled[key]
+		if !ok {
+			target = page
+		}
+		node := GraphNode{URL: key, Title: target.Title, StatusCode: target.StatusCode, Depth: -1}
+		if node.StatusCode != http.StatusOK {
+			continue
+		}
+		index[key] = len(graph.Nodes)
+		nodePages[key] = target
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	inSitemap := make(map[string]bool, len(sitemapURLs))
+	for _, entry := range sitemapURLs {
+		inSitemap[resolve(entry.Loc)] = true
+	}
+
+	// Edges, merged per source and target
+	edgeIndex := make(map[[2]string]int)
+	linked := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		source := node.URL
+		for _, link := range nodePages[source].LinkDetails {
+			if !link.Internal {
+				continue
+			}
+			target := resolve(link.URL)
+			if target == source {
+				continue
+			}
+			linked[target] = true
+			if _, ok := index[target]; !ok {
+				continue
+			}
+
+			key := [2]string{source, target}
+			i, ok := edgeIndex[key]
+			if !ok {
+				i = len(graph.Edges)
+				edgeIndex[key] = i
+				graph.Edges = append(graph.Edges, GraphEdge{Source: source, Target: target})
+			}
+			edge := &graph.Edges[i]
+			edge.Count++
+			if link.IsFollowed() {
+				edge.Followed++
+			}
+			if link.Anchor != "" && len(edge.Anchors) < maxEdgeAnchors && !slices.Contains(edge.Anchors, link.Anchor) {
+				edge.Anchors = append(edge.Anchors, link.Anchor)
+			}
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		graph.Nodes[index[edge.Source]].Outlinks++
+		graph.Nodes[index[edge.Target]].Inlinks++
+	}
+
+	graph.computeDepth(index)
+	graph.computePageRank(index)
+
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		node.InSitemap = inSitemap[node.URL]
+		if node.Outlinks == 0 {
+			node.DeadEnd = true
+			graph.DeadEnds = append(graph.DeadEnds, node.URL)
+		}
+	}
+
+	// Orphans include sitemap URLs that were not crawled
+	var orphans, notReached []string
+	for u := range inSitemap {
+		if linked[u] || u == graph.Home {
+			continue
+		}
+		i, crawled := index[u]
+		switch {
+		case finished:
+			orphans = append(orphans, u)
+			if crawled {
+				graph.Nodes[i].Orphan = true
+			}
+		case !crawled:
+			notReached = append(notReached, u)
+		}
+	}
+	sort.Strings(orphans)
+	sort.Strings(notReached)
+	graph.Orphans = orphans
+	graph.NotReached = notReached
+
+	return graph
+}
+
+// computeDepth sets the click depth of every node with a breadth-first
+// search from the homepage
+func (g *LinkGraph) computeDepth(index map[string]int) {
+	home, ok := index[g.Home]
+	if !ok {
+		return
+	}
+	adjacent := make([][]int, len(g.Nodes))
+	for _, edge := range g.Edges {
+		source := index[edge.Source]
+		adjacent[source] = append(adjacent[source], index[edge.Target])
+	}
+
+	g.Nodes[home].Depth = 0
+	queue := []int{home}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacent[current] {
+			if g.Nodes[next].Depth < 0 {
+				g.Nodes[next].Depth = g.Nodes[current].Depth + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+}
+
+// computePageRank computes the internal PageRank of every node over the
+// followed links. Dead ends spread their rank evenly over all pages, and the
+// ranks sum to 1.
+func (g *LinkGraph) computePageRank(index map[string]int) {
+	n := len(g.Nodes)
+	if n == 0 {
+		return
+	}
+	followed := make([]int, n)
+	for _, edge := range g.Edges {
+		if edge.Followed > 0 {
+			followed[index[edge.Source]] += edge.Followed
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		dangling := 0.0
+		for i, r := range rank {
+			if followed[i] == 0 {
+				dangling += r
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for _, edge := range g.Edges {
+			if edge.Followed == 0 {
+				continue
+			}
+			source := index[edge.Source]
+			next[index[edge.Target]] += pageRankDamping * rank[source] * float64(edge.Followed) / float64(followed[source])
+		}
+
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	for i := range g.Nodes {
+		g.Nodes[i].PageRank = rank[i]
+	}
+}
+
+// WriteGraphML writes the graph in the GraphML format, which Gephi, yEd and
+// Cytoscape import
+func (g *LinkGraph) WriteGraphML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, key := range []struct{ id, scope, name, kind string }{
+		{"url", "node", "url", "string"},
+		{"title", "node", "title", "string"},
+		{"status", "node", "status", "int"},
+		{"depth", "node", "depth", "int"},
+		{"inlinks", "node", "inlinks", "int"},
+		{"outlinks", "node", "outlinks", "int"},
+		{"pagerank", "node", "pagerank", "double"},
+		{"sitemap", "node", "in_sitemap", "boolean"},
+		{"orphan", "node", "orphan", "boolean"},
+		{"deadend", "node", "dead_end", "boolean"},
+		{"anchors", "edge", "anchors", "string"},
+		{"count", "edge", "count", "int"},
+		{"followed", "edge", "followed", "int"},
+	} {
+		fmt.Fprintf(bw, `  <key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", key.id, key.scope, key.name, key.kind)
+	}
+	bw.WriteString(`  <graph id="site" edgedefault="directed">` + "\n")
+
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		id := "n" + strconv.Itoa(i)
+		ids[node.URL] = id
+		fmt.Fprintf(bw, `    <node id="%s">`+"\n", id)
+		writeGraphMLData(bw, "url", node.URL)
+		writeGraphMLData(bw, "title", node.Title)
+		writeGraphMLData(bw, "status", strconv.Itoa(node.StatusCode))
+		writeGraphMLData(bw, "depth", strconv.Itoa(node.Depth))
+		writeGraphMLData(bw, "inlinks", strconv.Itoa(node.Inlinks))
+		writeGraphMLData(bw, "outlinks", strconv.Itoa(node.Outlinks))
+		writeGraphMLData(bw, "pagerank", strconv.FormatFloat(node.PageRank, 'g', 6, 64))
+		writeGraphMLData(bw, "sitemap", strconv.FormatBool(node.InSitemap))
+		writeGraphMLData(bw, "orphan", strconv.FormatBool(node.Orphan))
+		writeGraphMLData(bw, "deadend", strconv.FormatBool(node.DeadEnd))
+		bw.WriteString("    </node>\n")
+	}
+	for i, edge := range g.Edges {
+		fmt.Fprintf(bw, `    <edge id="e%d" source="%s" target="%s">`+"\n", i, ids[edge.Source], ids[edge.Target])
+		writeGraphMLData(bw, "anchors", strings.Join(edge.Anchors, " | "))
+		writeGraphMLData(bw, "count", strconv.Itoa(edge.Count))
+		writeGraphMLData(bw, "followed", strconv.Itoa(edge.Followed))
+		bw.WriteString("    </edge>\n")
+	}
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// writeGraphMLData writes an escaped <data> element
+func writeGraphMLData(w *bufio.Writer, key, value string) {
+	fmt.Fprintf(w, `      <data key="%s">`, key)
+	xml.EscapeText(w, []byte(value))
+	w.WriteString("</data>\n")
+}
+
+// WriteDOT writes the graph in the Graphviz DOT format. Nodes are labeled
+// with their path and sized by PageRank, nofollow-only edges are dashed.
+func (g *LinkGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph site {\n")
+	bw.WriteString("  rankdir=LR;\n  node [shape=box, style=rounded];\n")
+
+	maxRank := 0.0
+	for _, node := range g.Nodes {
+		maxRank = math.Max(maxRank, node.PageRank)
+	}
+	for _, node := range g.Nodes {
+		label := node.URL
+		if i := strings.Index(label, "://"); i >= 0 {
+			if j := strings.Index(label[i+3:], "/"); j >= 0 {
+				label = label[i+3+j:]
+			}
+		}
+		fontSize := 10.0
+		if maxRank > 0 {
+			fontSize += 14 * node.PageRank / maxRank
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, tooltip=%s, fontsize=%.1f",
+			dotQuote(node.URL), dotQuote(fmt.Sprintf("%s\ndepth %d", label, node.Depth)), dotQuote(node.Title), fontSize)
+		switch {
+		case node.Orphan:
+			bw.WriteString(", color=red")
+		case node.DeadEnd:
+			bw.WriteString(", color=orange")
+		}
+		bw.WriteString("];\n")
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [tooltip=%s", dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(strings.Join(edge.Anchors, " | ")))
+		if edge.Followed == 0 {
+			bw.WriteString(", style=dashed")
+		}
+		bw.WriteString("];\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote quotes a string as a DOT identifier
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+)
+
+// newGraphSite serves a site whose home page links to /a twice, to /b and
+// to /dup, which is canonicalized to /c. /b links to /c with nofollow, /c is
+// a dead end and /orphan is only listed in the sitemap.
+func newGraphSite(t *testing.T) string {
+	srv := newTestSite(t, map[string]string{
+		"/robots.txt": "User-agent: *\nAllow: /",
+		"/": `<html><head><title>Home</title></head><body>
+			<a href="/a">A</a><a href="/a">About</a><a href="/b">B</a><a href="/dup">C</a></body></html>`,
+		"/a": testPage("A", "/c", "/"),
+		"/b": `<html><head><title>B</title></head><body><a href="/c" rel="nofollow">C</a></body></html>`,
+		"/c": testPage("C"),
+		"/dup": `<html><head><title>C</title><link rel="canonical" href="{{base}}/c"></head>
+			<body><h1>C</h1></body></html>`,
+		"/orphan": testPage("Orphan"),
+		"/sitemap.xml": `<urlset><url><loc>{{base}}/</loc></url><url><loc>{{base}}/a</loc></url>
+			<url><loc>{{base}}/orphan</loc></url></urlset>`,
+	})
+	return srv.URL
+}
+
+func TestCrawlSiteLinkGraph(t *testing.T) {
+	base := newGraphSite(t)
+	site, err := newTestCrawler().CrawlSite(context.Background(), base, SiteCrawlOptions{Concurrency: 1, UseSitemaps: true})
+	if err != nil {
+		t.Fatalf("CrawlSite() error = %v", err)
+	}
+	graph := site.LinkGraph
+
+	nodes := make(map[string]GraphNode)
+	total := 0.0
+	for _, node := range graph.Nodes {
+		nodes[node.URL] = node
+		total += node.PageRank
+	}
+	if len(nodes) != 5 {
+		t.Fatalf("Nodes = %+v, want /, /a, /b, /c and /orphan", graph.Nodes)
+	}
+	if math.Abs(total-1) > 1e-6 {
+		t.Errorf("PageRanks sum to %f, want 1", total)
+	}
+
+	wantDepth := map[string]int{"/": 0, "/a": 1, "/b": 1, "/c": 1, "/orphan": -1}
+	for path, depth := range wantDepth {
+		if got := nodes[base+path].Depth; got != depth {
+			t.Errorf("Depth of %s = %d, want %d", path, got, depth)
+		}
+	}
+
+	// /c collects the links to /dup and is linked from /a; the nofollow link
+	// from /b passes nothing
+	rank := func(path string) float64 { return nodes[base+path].PageRank }
+	if !(rank("/c") > rank("/a") && rank("/a") > rank("/b") && rank("/b") > rank("/orphan")) {
+		t.Errorf("PageRank /c %f, /a %f, /b %f, /orphan %f; want them in this order",
+			rank("/c"), rank("/a"), rank("/b"), rank("/orphan"))
+	}
+
+	edges := make(map[[2]string]GraphEdge)
+	for _, edge := range graph.Edges {
+		edges[[2]string{edge.Source, edge.Target}] = edge
+	}
+	if edge := edges[[2]string{base + "/", base + "/a"}]; edge.Count != 2 || !slices.Equal(edge.Anchors, []string{"A", "About"}) {
+		t.Errorf("edge / -> /a = %+v, want both links and anchors merged", edge)
+	}
+	if edge, ok := edges[[2]string{base + "/", base + "/c"}]; !ok || edge.Followed != 1 {
+		t.Errorf("edge / -> /c = %+v, want the link to the canonicalized /dup", edge)
+	}
+	if edge := edges[[2]string{base + "/b", base + "/c"}]; edge.Count != 1 || edge.Followed != 0 {
+		t.Errorf("edge /b -> /c = %+v, want one nofollow link", edge)
+	}
+
+	if !slices.Equal(graph.Orphans, []string{base + "/orphan"}) || !nodes[base+"/orphan"].Orphan {
+		t.Errorf("Orphans = %v, want /orphan", graph.Orphans)
+	}
+	if len(graph.NotReached) > 0 {
+		t.Errorf("NotReached = %v, want none for a finished crawl", graph.NotReached)
+	}
+	if !slices.Equal(graph.DeadEnds, []string{base + "/c", base + "/orphan"}) && !slices.Equal(graph.DeadEnds, []string{base + "/orphan", base + "/c"}) {
+		t.Errorf("DeadEnds = %v, want /c and /orphan", graph.DeadEnds)
+	}
+}
+
+func TestCrawlSiteLinkGraphPageLimit(t *testing.T) {
+	base := newGraphSite(t)
+	site, err := newTestCrawler().CrawlSite(context.Background(), base, SiteCrawlOptions{Concurrency: 1, UseSitemaps: true, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("CrawlSite() error = %v", err)
+	}
+	graph := site.LinkGraph
+	// Pages that were not crawled may link to /orphan
+	if len(graph.Orphans) > 0 {
+		t.Errorf("Orphans = %v, want none for a crawl stopped by its page limit", graph.Orphans)
+	}
+	if !slices.Equal(graph.NotReached, []string{base + "/orphan"}) {
+		t.Errorf("NotReached = %v, want /orphan", graph.NotReached)
+	}
+}
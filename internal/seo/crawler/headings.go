@@ -0,0 +1,144 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Page regions derived from HTML5 landmarks
+const (
+	RegionMain   = "main"
+	RegionHeader = "header"
+	RegionNav    = "nav"
+	RegionAside  = "aside"
+	RegionFooter = "footer"
+)
+
+// Heading is an H1–H6 element of the page outline. Headings are stored in
+// document order; Parent is the index of the closest preceding heading of a
+// higher rank, or -1 for top-level headings.
+type Heading struct {
+	Level      int
+	Text       string
+	Position   int // index in document order
+	WordOffset int // words of page text before the heading
+	Parent     int
+	Region     string // main, header, nav, aside, footer or empty
+	ImageOnly  bool   // the text comes from the ALT text of an image
+}
+
+// addHeading appends a heading to the outline and links it to its parent
+func (c *Crawler) addHeading(n *html.Node, result *CrawlResult) {
+	level := int(n.Data[1] - '0')
+	text := strings.Join(strings.Fields(c.extractText(n)), " ")
+
+	heading := Heading{
+		Level:      level,
+		Text:       text,
+		Position:   len(result.Headings),
+		WordOffset: result.WordCount,
+		Parent:     -1,
+		Region:     c.region(n),
+	}
+	if text == "" {
+		heading.Text = c.imageAltText(n)
+		heading.ImageOnly = heading.Text != ""
+	}
+	for i := len(result.Headings) - 1; i >= 0; i-- {
+		if result.Headings[i].Level < level {
+			heading.Parent = i
+			break
+		}
+	}
+
+	result.Headings = append(result.Headings, heading)
+}
+
+// imageAltText returns the ALT texts of all images below a node
+func (c *Crawler) imageAltText(n *html.Node) string {
+	var alts []string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "img" {
+			if alt := strings.TrimSpace(c.getAttr(node, "alt")); alt != "" {
+				alts = append(alts, alt)
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(alts, " ")
+}
+
+// region returns the closest landmark region around a node
+func (c *Crawler) region(n *html.Node) string {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		switch strings.ToLower(c.getAttr(p, "role")) {
+		case "main":
+			return RegionMain
+		case "banner":
+			return RegionHeader
+		case "navigation":
+			return RegionNav
+		case "complementary":
+			return RegionAside
+		case "contentinfo":
+			return RegionFooter
+		}
+		switch p.Data {
+		case "main":
+			return RegionMain
+		case "nav":
+			return RegionNav
+		case "aside":
+			return RegionAside
+		case "header", "footer":
+			// header and footer inside article or section belong to that content
+			if isSectioningAncestor(p) {
+				continue
+			}
+			if p.Data == "header" {
+				return RegionHeader
+			}
+			return RegionFooter
+		}
+	}
+	return ""
+}
+
+// isSectioningAncestor reports whether a node is nested in sectioning content
+func isSectioningAncestor(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode {
+			switch p.Data {
+			case "article", "section", "main", "aside", "nav":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Outline renders the heading tree as indented text, one heading per line
+func (r *CrawlResult) Outline() string {
+	var b strings.Builder
+	for _, h := range r.Headings {
+		depth := 0
+		for p := h.Parent; p >= 0; p = r.Headings[p].Parent {
+			depth++
+		}
+		text := h.Text
+		if text == "" {
+			text = "(empty)"
+		}
+		fmt.Fprintf(&b, "%sH%d: %s\n", strings.Repeat("  ", depth), h.Level, text)
+	}
+	return b.String()
+}
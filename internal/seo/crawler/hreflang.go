@@ -0,0 +1,325 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Hreflang sources
+const (
+	HreflangSourceHTML    = "html"
+	HreflangSourceHeader  = "header"
+	HreflangSourceSitemap = "sitemap"
+)
+
+// Hreflang issue types
+const (
+	HreflangIssueInvalidCode     = "invalid_code"
+	HreflangIssueMissingReturn   = "missing_return_link"
+	HreflangIssueMissingSelf     = "missing_self_reference"
+	HreflangIssueMissingXDefault = "missing_x_default"
+	HreflangIssueConflict        = "conflicting_targets"
+	HreflangIssueNon200          = "target_non_200"
+	HreflangIssueNonCanonical    = "target_not_canonical"
+	HreflangIssueLangMismatch    = "language_mismatch"
+)
+
+// HreflangXDefault is the hreflang value of the fallback page
+const HreflangXDefault = "x-default"
+
+// HreflangLink is an alternate language version of a page
+type HreflangLink struct {
+	Lang   string
+	URL    string
+	Source string // html, header or sitemap
+}
+
+// HreflangIssue is a problem found when validating hreflang annotations
+// across the pages of a site crawl
+type HreflangIssue struct {
+	URL    string
+	Lang   string
+	Target string
+	Issue  string
+	Detail string
+}
+
+// isoLanguages are the ISO 639-1 language codes
+var isoLanguages = isoCodeSet(`aa ab ae af ak am an ar as av ay az ba be bg bh bi bm bn bo br bs ca ce ch co cr cs
+	cu cv cy da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht hu hy
+	hz ia id ie ig ii ik io is it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky la lb lg li ln lo lt
+	lu lv mg mh mi mk ml mn mr ms mt my na nb nd ne ng nl nn no nr nv ny oc oj om or os pa pi pl ps pt qu rm
+	rn ro ru rw sa sc sd se sg si sk sl sm sn so sq sr ss st su sv sw ta te tg th ti tk tl tn to tr ts tt tw
+	ty ug uk ur uz ve vi vo wa wo xh yi yo za zh zu`)
+
+// isoRegions are the ISO 3166-1 alpha-2 country codes plus 419 (Latin America)
+var isoRegions = isoCodeSet(`ad ae af ag ai al am ao aq ar as at au aw ax az ba bb bd be bf bg bh bi bj bl bm bn
+	bo bq br bs bt bv bw by bz ca cc cd cf cg ch ci ck cl cm cn co cr cu cv cw cx cy cz de dj dk dm do dz ec
+	ee eg eh er es et fi fj fk fm fo fr ga gb gd ge gf gg gh gi gl gm gn gp gq gr gs gt gu gw gy hk hm hn hr
+	ht hu id ie il im in io iq ir is it je jm jo jp ke kg kh ki km kn kp kr kw ky kz la lb lc li lk lr ls lt
+	lu lv ly ma mc md me mf mg mh mk ml mm mn mo mp mq mr ms mt mu mv mw mx my mz na nc ne nf ng ni nl no np
+	nr nu nz om pa pe pf pg ph pk pl pm pn pr ps pt pw py qa re ro rs ru rw sa sb sc sd se sg sh si sj sk sl
+	sm sn so sr ss st sv sx sy sz tc td tf tg th tj tk tl tm tn to tr tt tv tw tz ua ug um us uy uz va vc ve
+	vg vi vn vu wf ws ye yt za zm zw 419`)
+
+// isoCodeSet turns a whitespace-separated code list into a set
+func isoCodeSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, code := range strings.Fields(list) {
+		set[code] = true
+	}
+	return set
+}
+
+// HreflangCodeError returns why an hreflang value is invalid, or "" if it is
+// a valid ISO 639-1 language with optional script and ISO 3166-1 region
+func HreflangCodeError(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == HreflangXDefault {
+		return ""
+	}
+	if strings.Contains(code, "_") {
+		return fmt.Sprintf("%q uses an underscore instead of a hyphen", code)
+	}
+	parts := strings.Split(code, "-")
+	if !isoLanguages[parts[0]] {
+		if isoRegions[parts[0]] && len(parts) == 1 {
+			return fmt.Sprintf("%q is a region, hreflang must start with a language code", code)
+		}
+		return fmt.Sprintf("%q is not an ISO 639-1 language code", parts[0])
+	}
+	rest := parts[1:]
+	if len(rest) > 0 && len(rest[0]) == 4 {
+		// Optional ISO 15924 script such as zh-Hant
+		rest = rest[1:]
+	}
+	switch len(rest) {
+	case 0:
+		return ""
+	case 1:
+		if isoRegions[rest[0]] {
+			return ""
+		}
+		if rest[0] == "uk" {
+			return `"uk" is not an ISO 3166-1 region, use "gb" for the United Kingdom`
+		}
+		return fmt.Sprintf("%q is not an ISO 3166-1 region code", rest[0])
+	}
+	return fmt.Sprintf("%q has too many subtags", code)
+}
+
+// addHreflang records an alternate language version of the page
+func (r *CrawlResult) addHreflang(lang, href, source string) {
+	lang = strings.TrimSpace(lang)
+	if lang == "" || href == "" {
+		return
+	}
+	r.Hreflang = append(r.Hreflang, HreflangLink{Lang: lang, URL: href, Source: source})
+}
+
+// parseLinkHeader records hreflang alternates from HTTP Link headers, e.g.
+// Link: <https://example.com/de/>; rel="alternate"; hreflang="de"
+func (r *CrawlResult) parseLinkHeader(header http.Header, baseURL *url.URL) {
+	for _, value := range header.Values("Link") {
+		for _, entry := range splitLinkHeader(value) {
+			target, params, ok := strings.Cut(entry, ";")
+			if !ok {
+				continue
+			}
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			var rel, hreflang string
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(param, "=")
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "rel":
+					rel = strings.ToLower(val)
+				case "hreflang":
+					hreflang = val
+				}
+			}
+			if hreflang == "" || !hasRel(rel, "alternate") {
+				continue
+			}
+			href, err := url.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				continue
+			}
+			r.addHreflang(hreflang, baseURL.ResolveReference(href).String(), HreflangSourceHeader)
+		}
+	}
+}
+
+// splitLinkHeader splits a Link header value at commas outside of <...>
+func splitLinkHeader(value string) []string {
+	var entries []string
+	depth, start := 0, 0
+	for i, ch := range value {
+		switch ch {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				entries = append(entries, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(entries, value[start:])
+}
+
+// hasRel reports whether a space-separated rel value contains name
+func hasRel(rel, name string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// auditHreflang validates the hreflang annotations of all crawled pages.
+// Annotations from HTML, HTTP headers and sitemaps are combined per page.
+func auditHreflang(pages []*CrawlResult, sitemapURLs []SitemapURL, normalizer *URLNormalizer) []HreflangIssue {
+	normalize := func(u string) string {
+		if n, err := normalizer.Normalize(u); err == nil {
+			return n
+		}
+		return u
+	}
+
+	crawled := make(map[string]*CrawlResult, len(pages))
+	for _, page := range pages {
+		crawled[normalize(page.URL)] = page
+	}
+
+	// Collect annotations per page
+	annotations := make(map[string][]HreflangLink)
+	var order []string
+	add := func(pageURL string, link HreflangLink) {
+		key := normalize(pageURL)
+		if _, ok := annotations[key]; !ok {
+			order = append(order, key)
+		}
+		annotations[key] = append(annotations[key], link)
+	}
+	for _, page := range pages {
+		// Annotations of redirected URLs belong to the redirect target
+		if page.StatusCode != http.StatusOK || len(page.RedirectChain) > 0 {
+			continue
+		}
+		for _, link := range page.Hreflang {
+			add(page.URL, link)
+		}
+	}
+	for _, entry := range sitemapURLs {
+		for _, alt := range entry.Alternates {
+			add(entry.Loc, HreflangLink{Lang: alt.Hreflang, URL: alt.Href, Source: HreflangSourceSitemap})
+		}
+	}
+
+	var issues []HreflangIssue
+	for _, pageURL := range order {
+		links := annotations[pageURL]
+		targets := make(map[string]string) // lowercase lang -> normalized target
+		hasSelf, hasXDefault := false, false
+
+		for _, link := range links {
+			lang := strings.ToLower(link.Lang)
+			target := normalize(link.URL)
+
+			if msg := HreflangCodeError(lang); msg != "" {
+				issues = append(issues, HreflangIssue{URL: pageURL, Lang: link.Lang, Target: link.URL, Issue: HreflangIssueInvalidCode, Detail: msg})
+				continue
+			}
+			if previous, ok := targets[lang]; ok {
+				if previous != target {
+					issues = append(issues, HreflangIssue{
+						URL: pageURL, Lang: link.Lang, Target: link.URL, Issue: HreflangIssueConflict,
+						Detail: fmt.Sprintf("%s points to both %s and %s", link.Lang, previous, target),
+					})
+				}
+				continue
+			}
+			targets[lang] = target
+			if lang == HreflangXDefault {
+				hasXDefault = true
+			}
+			if target == pageURL {
+				hasSelf = true
+			}
+		}
+
+		if !hasSelf {
+			issues = append(issues, HreflangIssue{URL: pageURL, Issue: HreflangIssueMissingSelf, Detail: "the page does not list itself among its language versions"})
+		}
+		if !hasXDefault {
+			issues = append(issues, HreflangIssue{URL: pageURL, Issue: HreflangIssueMissingXDefault, Detail: "no x-default fallback for other languages"})
+		}
+
+		langs := make([]string, 0, len(targets))
+		for lang := range targets {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			target := targets[lang]
+			if target == pageURL {
+				continue
+			}
+			page, ok := crawled[target]
+			if !ok {
+				// Not crawled, nothing known about the target
+				continue
+			}
+
+			switch {
+			case len(page.RedirectChain) > 0:
+				issues = append(issues, HreflangIssue{URL: pageURL, Lang: lang, Target: target, Issue: HreflangIssueNon200, Detail: "target redirects to " + page.FinalURL})
+				continue
+			case page.StatusCode != http.StatusOK:
+				issues = append(issues, HreflangIssue{URL: pageURL, Lang: lang, Target: target, Issue: HreflangIssueNon200, Detail: fmt.Sprintf("target returns status %d", page.StatusCode)})
+				continue
+			case page.Indexability.Reason == IndexabilityCanonicalized || page.Indexability.Reason == IndexabilityNoindex:
+				issues = append(issues, HreflangIssue{URL: pageURL, Lang: lang, Target: target, Issue: HreflangIssueNonCanonical, Detail: page.Indexability.Detail})
+				continue
+			}
+
+			// The target has to link back to this page
+			if lang != HreflangXDefault {
+				returned := false
+				for _, back := range annotations[target] {
+					if normalize(back.URL) == pageURL {
+						returned = true
+						break
+					}
+				}
+				if !returned {
+					issues = append(issues, HreflangIssue{URL: pageURL, Lang: lang, Target: target, Issue: HreflangIssueMissingReturn, Detail: "target has no hreflang annotation pointing back"})
+				}
+			}
+
+			// The declared language should match the language of the target
+			if lang != HreflangXDefault && page.Language != "" {
+				declared, _, _ := strings.Cut(lang, "-")
+				actual, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(page.Language, "_", "-")), "-")
+				if declared != actual {
+					issues = append(issues, HreflangIssue{
+						URL: pageURL, Lang: lang, Target: target, Issue: HreflangIssueLangMismatch,
+						Detail: fmt.Sprintf("target declares <html lang=%q>", page.Language),
+					})
+				}
+			}
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,38 @@
+package crawler
+
+import "testing"
+
+func TestHreflangCodeError(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"de", ""},
+		{"en-US", ""},
+		{"EN-us", ""},
+		{" fr-ca ", ""},
+		{"x-default", ""},
+		{"X-Default", ""},
+		{"uk", ""},
+		{"zh-Hant", ""},
+		{"zh-Hant-TW", ""},
+		{"es-419", ""},
+		{"en_US", `"en_us" uses an underscore instead of a hyphen`},
+		{"gb", `"gb" is a region, hreflang must start with a language code`},
+		{"us-en", `"us" is not an ISO 639-1 language code`},
+		{"xx", `"xx" is not an ISO 639-1 language code`},
+		{"eng", `"eng" is not an ISO 639-1 language code`},
+		{"", `"" is not an ISO 639-1 language code`},
+		{"en-uk", `"uk" is not an ISO 3166-1 region, use "gb" for the United Kingdom`},
+		{"de-xx", `"xx" is not an ISO 3166-1 region code`},
+		{"de-", `"" is not an ISO 3166-1 region code`},
+		{"de-de-de", `"de-de-de" has too many subtags`},
+		{"zh-Hant-TW-x", `"zh-hant-tw-x" has too many subtags`},
+	}
+
+	for _, tt := range tests {
+		if got := HreflangCodeError(tt.code); got != tt.want {
+			t.Errorf("HreflangCodeError(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
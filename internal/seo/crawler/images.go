@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ImageSource is a <source> of a <picture> element
+type ImageSource struct {
+	Type   string
+	Srcset string
+	Media  string
+}
+
+// addImage records an <img> element with its declared attributes
+func (c *Crawler) addImage(n *html.Node, result *CrawlResult, baseURL *url.URL) {
+	img := Image{
+		Src:    c.getAttr(n, "src"),
+		Alt:    c.getAttr(n, "alt"),
+		Title:  c.getAttr(n, "title"),
+		Width:  htmlDimension(c.getAttr(n, "width")),
+		Height: htmlDimension(c.getAttr(n, "height")),
+		Srcset: strings.TrimSpace(c.getAttr(n, "srcset")),
+		Sizes:  strings.TrimSpace(c.getAttr(n, "sizes")),
+		Lazy:   strings.EqualFold(c.getAttr(n, "loading"), "lazy"),
+	}
+	if img.Src == "" {
+		return
+	}
+	img.Src = c.makeAbsolute(img.Src, baseURL)
+
+	if n.Parent != nil && n.Parent.Type == html.ElementNode && n.Parent.Data == "picture" {
+		img.InPicture = true
+		for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+			if s.Type == html.ElementNode && s.Data == "source" {
+				img.Sources = append(img.Sources, ImageSource{
+					Type:   strings.ToLower(strings.TrimSpace(c.getAttr(s, "type"))),
+					Srcset: strings.TrimSpace(c.getAttr(s, "srcset")),
+					Media:  c.getAttr(s, "media"),
+				})
+			}
+		}
+	}
+
+	result.Images = append(result.Images, img)
+}
+
+// htmlDimension parses a width or height attribute, which is a number of
+// CSS pixels. Percentages and invalid values yield 0.
+func htmlDimension(value string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// HasModernSource reports whether the image is available as WebP or AVIF,
+// either directly or through a <picture> source
+func (img Image) HasModernSource() bool {
+	if isModernImageType(img.ContentType) {
+		return true
+	}
+	for _, source := range img.Sources {
+		if isModernImageType(source.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// isModernImageType reports whether a MIME type is WebP or AVIF
+func isModernImageType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "image/webp") || strings.Contains(contentType, "image/avif")
+}
+
+// applyImageChecks copies the fetched size, type and intrinsic dimensions of
+// image assets to the images of the page
+func (r *CrawlResult) applyImageChecks() {
+	fetched := make(map[string]Asset, len(r.Assets))
+	for _, asset := range r.Assets {
+		if asset.Kind == AssetImage && asset.StatusCode != 0 {
+			fetched[asset.URL] = asset
+		}
+	}
+	for i := range r.Images {
+		img := &r.Images[i]
+		asset, ok := fetched[img.Src]
+		if !ok {
+			continue
+		}
+		img.Checked = true
+		img.StatusCode = asset.StatusCode
+		img.ContentType = asset.Transfer.ContentType
+		img.Size = asset.Transfer.TransferSize
+		img.ActualWidth = asset.Width
+		img.ActualHeight = asset.Height
+	}
+}
+
+// avifDimensions reads the dimensions from the image spatial extents
+// ("ispe") property of an AVIF file
+func avifDimensions(header []byte) (int, int, error) {
+	i := bytes.Index(header, []byte("ispe"))
+	if i < 0 || len(header) < i+16 {
+		return 0, 0, fmt.Errorf("no ispe box found")
+	}
+	// Box type, 4 bytes version and flags, then 32-bit width and height
+	width := binary.BigEndian.Uint32(header[i+8 : i+12])
+	height := binary.BigEndian.Uint32(header[i+12 : i+16])
+	return int(width), int(height), nil
+}
@@ -0,0 +1,230 @@
+package crawler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Indexability reasons
+const (
+	IndexabilityBlockedByRobots = "blocked_by_robots_txt"
+	IndexabilityNon200          = "non_200"
+	IndexabilityRedirected      = "redirected"
+	IndexabilityNoindex         = "noindex"
+	IndexabilityCanonicalized   = "canonicalized"
+)
+
+// indexabilityBot is the crawler whose directives decide the indexability verdict
+const indexabilityBot = "googlebot"
+
+// knownRobotsBots are the bot-specific meta tag names that are evaluated
+var knownRobotsBots = map[string]bool{
+	"googlebot":       true,
+	"googlebot-news":  true,
+	"googlebot-image": true,
+	"bingbot":         true,
+	"msnbot":          true,
+	"slurp":           true,
+	"yandex":          true,
+	"baiduspider":     true,
+	"duckduckbot":     true,
+	"applebot":        true,
+}
+
+// RobotsDirectives are the indexing directives from meta robots tags and
+// X-Robots-Tag headers
+type RobotsDirectives struct {
+	Noindex          bool
+	Nofollow         bool
+	Noarchive        bool
+	Nosnippet        bool
+	Noimageindex     bool
+	Notranslate      bool
+	MaxSnippet       *int
+	MaxImagePreview  string
+	MaxVideoPreview  *int
+	UnavailableAfter string
+	Sources          []string // meta, x-robots-tag
+}
+
+// Indexability is the verdict whether a page can be indexed and why not
+type Indexability struct {
+	Indexable bool
+	Reason    string
+	Detail    string
+}
+
+// apply parses a comma-separated directive list into d
+func (d *RobotsDirectives) apply(value, source string) {
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		name, arg, _ := strings.Cut(directive, ":")
+		name = strings.TrimSpace(name)
+		arg = strings.TrimSpace(arg)
+
+		switch name {
+		case "noindex":
+			d.Noindex = true
+		case "nofollow":
+			d.Nofollow = true
+		case "none":
+			d.Noindex = true
+			d.Nofollow = true
+		case "noarchive", "nocache":
+			d.Noarchive = true
+		case "nosnippet":
+			d.Nosnippet = true
+		case "noimageindex":
+			d.Noimageindex = true
+		case "notranslate":
+			d.Notranslate = true
+		case "max-snippet":
+			if n, err := strconv.Atoi(arg); err == nil {
+				d.MaxSnippet = minLimit(d.MaxSnippet, n)
+			}
+		case "max-video-preview":
+			if n, err := strconv.Atoi(arg); err == nil {
+				d.MaxVideoPreview = minLimit(d.MaxVideoPreview, n)
+			}
+		case "max-image-preview":
+			d.MaxImagePreview = arg
+		case "unavailable_after":
+			d.UnavailableAfter = arg
+		default:
+			continue
+		}
+		d.addSource(source)
+	}
+}
+
+// merge combines two directive sets; the most restrictive value wins
+func (d RobotsDirectives) merge(other RobotsDirectives) RobotsDirectives {
+	d.Noindex = d.Noindex || other.Noindex
+	d.Nofollow = d.Nofollow || other.Nofollow
+	d.Noarchive = d.Noarchive || other.Noarchive
+	d.Nosnippet = d.Nosnippet || other.Nosnippet
+	d.Noimageindex = d.Noimageindex || other.Noimageindex
+	d.Notranslate = d.Notranslate || other.Notranslate
+	if other.MaxSnippet != nil {
+		d.MaxSnippet = minLimit(d.MaxSnippet, *other.MaxSnippet)
+	}
+	if other.MaxVideoPreview != nil {
+		d.MaxVideoPreview = minLimit(d.MaxVideoPreview, *other.MaxVideoPreview)
+	}
+	if other.MaxImagePreview != "" {
+		d.MaxImagePreview = other.MaxImagePreview
+	}
+	if other.UnavailableAfter != "" {
+		d.UnavailableAfter = other.UnavailableAfter
+	}
+	sources := append([]string{}, d.Sources...)
+	d.Sources = sources
+	for _, source := range other.Sources {
+		d.addSource(source)
+	}
+	return d
+}
+
+func (d *RobotsDirectives) addSource(source string) {
+	for _, s := range d.Sources {
+		if s == source {
+			return
+		}
+	}
+	d.Sources = append(d.Sources, source)
+}
+
+// minLimit returns the smaller limit, treating -1 as "no limit"
+func minLimit(current *int, n int) *int {
+	if current == nil || (*current == -1 && n != -1) || (n != -1 && n < *current) {
+		return &n
+	}
+	return current
+}
+
+// parseMetaRobots records a <meta name="robots"> or bot-specific meta tag
+func (r *CrawlResult) parseMetaRobots(name, content string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "robots" {
+		r.RobotsDirectives.apply(content, "meta")
+		return
+	}
+	if knownRobotsBots[name] {
+		r.addBotDirectives(name, content, "meta")
+	}
+}
+
+// parseXRobotsTag records all X-Robots-Tag header values. A value may be
+// prefixed with the bot it applies to, e.g. "googlebot: noindex".
+func (r *CrawlResult) parseXRobotsTag(header http.Header) {
+	for _, value := range header.Values("X-Robots-Tag") {
+		prefix, rest, found := strings.Cut(value, ":")
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if found && !strings.ContainsAny(prefix, ", ") && !isRobotsDirectiveName(prefix) {
+			r.addBotDirectives(prefix, rest, "x-robots-tag")
+			continue
+		}
+		r.RobotsDirectives.apply(value, "x-robots-tag")
+	}
+}
+
+func (r *CrawlResult) addBotDirectives(bot, content, source string) {
+	if r.BotRobotsDirectives == nil {
+		r.BotRobotsDirectives = make(map[string]RobotsDirectives)
+	}
+	directives := r.BotRobotsDirectives[bot]
+	directives.apply(content, source)
+	r.BotRobotsDirectives[bot] = directives
+}
+
+// isRobotsDirectiveName reports whether name is a directive taking an argument
+func isRobotsDirectiveName(name string) bool {
+	switch name {
+	case "max-snippet", "max-image-preview", "max-video-preview", "unavailable_after":
+		return true
+	}
+	return false
+}
+
+// EffectiveRobotsDirectives returns the directives that apply to the given
+// bot: the generic ones combined with the bot-specific ones
+func (r *CrawlResult) EffectiveRobotsDirectives(bot string) RobotsDirectives {
+	directives := r.RobotsDirectives
+	if specific, ok := r.BotRobotsDirectives[strings.ToLower(bot)]; ok {
+		directives = directives.merge(specific)
+	}
+	return directives
+}
+
+// IsNoindex reports whether the page opts out of indexing via meta robots
+// or the X-Robots-Tag header
+func (r *CrawlResult) IsNoindex() bool {
+	return r.EffectiveRobotsDirectives(indexabilityBot).Noindex
+}
+
+// IsCanonicalized reports whether the canonical URL of the page points to
+// another URL. Both URLs are normalized, so a canonical that only differs in
+// the case of the host, a default port or a fragment points to the page
+// itself.
+func (r *CrawlResult) IsCanonicalized() bool {
+	return r.CanonicalURL != "" && !sameNormalizedURL(&URLNormalizer{TrackingParams: []string{}}, r.CanonicalURL, r.FinalURL)
+}
+
+// computeIndexability derives the indexability verdict of a crawled URL.
+// Problems of the final page take precedence over the URL being a redirect.
+func (r *CrawlResult) computeIndexability() Indexability {
+	switch {
+	case r.BlockedByRobotsTxt:
+		return Indexability{Reason: IndexabilityBlockedByRobots, Detail: "URL is disallowed by robots.txt"}
+	case r.StatusCode != http.StatusOK:
+		return Indexability{Reason: IndexabilityNon200, Detail: "status code " + strconv.Itoa(r.StatusCode)}
+	case r.IsNoindex():
+		return Indexability{Reason: IndexabilityNoindex, Detail: "robots directives contain noindex"}
+	case r.IsCanonicalized():
+		return Indexability{Reason: IndexabilityCanonicalized, Detail: "canonical points to " + r.CanonicalURL}
+	case len(r.RedirectChain) > 0:
+		return Indexability{Reason: IndexabilityRedirected, Detail: "URL redirects to " + r.FinalURL}
+	}
+	return Indexability{Indexable: true}
+}
@@ -0,0 +1,30 @@
+package crawler
+
+import "testing"
+
+func TestIsCanonicalized(t *testing.T) {
+	tests := []struct {
+		name      string
+		canonical string
+		final     string
+		want      bool
+	}{
+		{"no canonical", "", "https://example.com/a", false},
+		{"same URL", "https://example.com/a", "https://example.com/a", false},
+		{"host case and default port", "https://EXAMPLE.com:443/a", "https://example.com/a", false},
+		{"fragment", "https://example.com/a#top", "https://example.com/a", false},
+		{"other path", "https://example.com/b", "https://example.com/a", true},
+		{"other query", "https://example.com/a?page=2", "https://example.com/a", true},
+		{"tracking parameters are kept", "https://example.com/a?utm_source=x", "https://example.com/a", true},
+		{"invalid canonical", "not a url", "https://example.com/a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CrawlResult{CanonicalURL: tt.canonical, FinalURL: tt.final}
+			if got := r.IsCanonicalized(); got != tt.want {
+				t.Errorf("IsCanonicalized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLinkCheckConcurrency is the default number of parallel link checks
+const defaultLinkCheckConcurrency = 10
+
+// Link check failure kinds
+const (
+	LinkErrorHTTP       = "http_error"
+	LinkErrorTimeout    = "timeout"
+	LinkErrorDNS        = "dns"
+	LinkErrorTLS        = "tls"
+	LinkErrorConnection = "connection"
+	LinkErrorBlocked    = "blocked_by_robots_txt"
+)
+
+// Link source kinds
+const (
+	LinkSourceAnchor = "link"
+	LinkSourceImage  = "image"
+)
+
+// LinkCheckOptions configures a link check. Zero values fall back to defaults.
+type LinkCheckOptions struct {
+	Concurrency int
+	MaxPerHost  int
+	// Timeout per checked URL; defaults to the crawler timeout
+	Timeout time.Duration
+	// SkipExternal only checks URLs on the host of the referencing page
+	SkipExternal bool
+	// SkipImages only checks <a href> links
+	SkipImages bool
+}
+
+// LinkSource is a reference to a checked URL
+type LinkSource struct {
+	Page   string
+	Anchor string
+	Kind   string // link or image
+}
+
+// LinkStatus is the check result of one URL
+type LinkStatus struct {
+	URL        string
+	Internal   bool
+	StatusCode int
+	FinalURL   string
+	Redirected bool
+	ErrorKind  string
+	Error      string
+	Sources    []LinkSource
+}
+
+// IsBroken reports whether the URL failed to resolve. URLs blocked by
+// robots.txt were not checked and are not broken.
+func (s LinkStatus) IsBroken() bool {
+	if s.ErrorKind == LinkErrorBlocked {
+		return false
+	}
+	return s.ErrorKind != "" || s.StatusCode >= 400
+}
+
+// LinkCheckReport is the result of checking the links of a set of pages
+type LinkCheckReport struct {
+	Checked int
+	Results []LinkStatus
+}
+
+// Broken returns the URLs that failed to resolve
+func (r *LinkCheckReport) Broken() []LinkStatus {
+	var broken []LinkStatus
+	for _, status := range r.Results {
+		if status.IsBroken() {
+			broken = append(broken, status)
+		}
+	}
+	return broken
+}
+
+// linkCheckCache shares check results across the pages of a link check
+type linkCheckCache struct {
+	mu      sync.Mutex
+	entries map[string]*linkCheckEntry
+}
+
+// linkCheckEntry is a cached check; ready is closed once status is set
+type linkCheckEntry struct {
+	ready  chan struct{}
+	status LinkStatus
+}
+
+// CheckLinks verifies every link and image URL referenced by the given
+// pages. Each URL is checked once per call, so a later call sees links that
+// were fixed in the meantime.
+func (c *Crawler) CheckLinks(ctx context.Context, pages []*CrawlResult, opts LinkCheckOptions) (*LinkCheckReport, error) {
+	return c.checkLinks(ctx, &linkCheckCache{}, pages, opts, nil)
+}
+
+// checkLinks checks the links of pages; known returns results that are
+// already known from crawling, e.g. for internal pages of a site crawl
+func (c *Crawler) checkLinks(ctx context.Context, cache *linkCheckCache, pages []*CrawlResult, opts LinkCheckOptions, known func(string) (LinkStatus, bool)) (*LinkCheckReport, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLinkCheckConcurrency
+	}
+	perHost := opts.MaxPerHost
+	if perHost <= 0 {
+		perHost = defaultMaxPerHost
+	}
+
+	// Collect the targets and who references them
+	targets := make(map[string]*LinkStatus)
+	var order []string
+	add := func(rawURL string, internal bool, source LinkSource) {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		u.RawFragment = ""
+		key := u.String()
+		target, ok := targets[key]
+		if !ok {
+			target = &LinkStatus{URL: key, Internal: internal}
+			targets[key] = target
+			order = append(order, key)
+		}
+		target.Sources = append(target.Sources, source)
+	}
+	for _, page := range pages {
+		pageURL, err := url.Parse(page.FinalURL)
+		if err != nil {
+			continue
+		}
+		scope := newScopeChecker(CrawlScope{}, pageURL)
+		for _, link := range page.LinkDetails {
+			if opts.SkipExternal && !link.Internal {
+				continue
+			}
+			add(link.URL, link.Internal, LinkSource{Page: page.URL, Anchor: link.Anchor, Kind: LinkSourceAnchor})
+		}
+		if opts.SkipImages {
+			continue
+		}
+		for _, img := range page.Images {
+			u, err := url.Parse(img.Src)
+			if err != nil {
+				continue
+			}
+			internal := scope.isInternalHost(strings.ToLower(u.Host))
+			if opts.SkipExternal && !internal {
+				continue
+			}
+			add(img.Src, internal, LinkSource{Page: page.URL, Anchor: img.Alt, Kind: LinkSourceImage})
+		}
+	}
+
+	// Check the targets with a bounded pool of workers
+	jobs := make(chan *LinkStatus)
+	hosts := newHostLimiter(perHost)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				var status LinkStatus
+				ok := false
+				if known != nil {
+					status, ok = known(target.URL)
+				}
+				if !ok {
+					status = c.checkLink(ctx, cache, hosts, target.URL, target.Internal, opts.Timeout)
+				}
+				target.StatusCode = status.StatusCode
+				target.FinalURL = status.FinalURL
+				target.Redirected = status.Redirected
+				target.ErrorKind = status.ErrorKind
+				target.Error = status.Error
+			}
+		}()
+	}
+	for _, key := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- targets[key]
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &LinkCheckReport{Results: make([]LinkStatus, 0, len(order))}
+	for _, key := range order {
+		target := targets[key]
+		if target.StatusCode == 0 && target.ErrorKind == "" {
+			// Not checked because the context was cancelled
+			continue
+		}
+		report.Results = append(report.Results, *target)
+	}
+	report.Checked = len(report.Results)
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].IsBroken() && !report.Results[j].IsBroken()
+	})
+
+	return report, ctx.Err()
+}
+
+// checkLink returns the cached status of a URL or checks it
+func (c *Crawler) checkLink(ctx context.Context, cache *linkCheckCache, hosts *hostLimiter, urlStr string, internal bool, timeout time.Duration) LinkStatus {
+	cache.mu.Lock()
+	if cache.entries == nil {
+		cache.entries = make(map[string]*linkCheckEntry)
+	}
+	entry, ok := cache.entries[urlStr]
+	if !ok {
+		entry = &linkCheckEntry{ready: make(chan struct{})}
+		cache.entries[urlStr] = entry
+	}
+	cache.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+			return entry.status
+		case <-ctx.Done():
+			return LinkStatus{}
+		}
+	}
+
+	entry.status = c.fetchLinkStatus(ctx, hosts, urlStr, internal, timeout)
+	if ctx.Err() != nil {
+		// Do not cache results of cancelled checks
+		cache.mu.Lock()
+		delete(cache.entries, urlStr)
+		cache.mu.Unlock()
+		entry.status = LinkStatus{}
+	}
+	close(entry.ready)
+	return entry.status
+}
+
+// fetchLinkStatus requests a URL with HEAD and falls back to GET for
+// servers that do not answer HEAD requests properly. robots.txt is honored
+// for internal URLs; external URLs only receive a single check request.
+func (c *Crawler) fetchLinkStatus(ctx context.Context, hosts *hostLimiter, urlStr string, internal bool, timeout time.Duration) LinkStatus {
+	status := LinkStatus{URL: urlStr}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		status.ErrorKind = LinkErrorConnection
+		status.Error = fmt.Sprintf("invalid URL: %v", err)
+		return status
+	}
+
+	if c.respectRobotsTxt && internal {
+		robots, err := c.robotsFor(ctx, u)
+		if err == nil && !robots.IsAllowed(c.userAgent, u) {
+			status.ErrorKind = LinkErrorBlocked
+			status.Error = "URL is disallowed by robots.txt"
+			return status
+		}
+	}
+
+	release, err := hosts.acquire(ctx, u.Host)
+	if err != nil {
+		return status
+	}
+	defer release()
+
+	if timeout <= 0 {
+		timeout = c.timeout
+	}
+
+	resp, err := c.linkRequest(ctx, "HEAD", urlStr, timeout)
+	if err == nil && resp.StatusCode >= 400 {
+		// Many servers reject HEAD (405, 501) or answer it differently than GET
+		resp, err = c.linkRequest(ctx, "GET", urlStr, timeout)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancelled while waiting for the host or during the request,
+			// so the URL was not checked
+			return LinkStatus{URL: urlStr}
+		}
+		status.ErrorKind, status.Error = classifyLinkError(err)
+		return status
+	}
+
+	status.StatusCode = resp.StatusCode
+	status.FinalURL = resp.Request.URL.String()
+	status.Redirected = status.FinalURL != urlStr
+	if resp.StatusCode >= 400 {
+		status.ErrorKind = LinkErrorHTTP
+		status.Error = resp.Status
+	}
+	return status
+}
+
+// linkRequest performs a single check request and discards the body. The
+// timeout covers the request only, not the wait for the host's rate limit,
+// so a host that pushed back does not turn its links into timeouts.
+func (c *Crawler) linkRequest(ctx context.Context, method, urlStr string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "*/*")
+
+	client := *c.client
+	client.Timeout = timeout
+	resp, _, err := c.sendRequest(ctx, &client, req, c.robotsDelay(ctx, req.URL))
+	if err != nil {
+		return nil, err
+	}
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// classifyLinkError maps a request error to a failure kind and message
+func classifyLinkError(err error) (string, string) {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return LinkErrorDNS, dnsErr.Error()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return LinkErrorTimeout, "request timed out"
+	case errors.As(err, &certErr), errors.As(err, &hostnameErr), errors.As(err, &authorityErr), errors.As(err, &invalidErr):
+		return LinkErrorTLS, err.Error()
+	}
+	return LinkErrorConnection, err.Error()
+}
+
+// pageLinkStatus converts a crawled page into a link status
+func pageLinkStatus(page *CrawlResult) (LinkStatus, bool) {
+	if page.BlockedByRobotsTxt {
+		return LinkStatus{ErrorKind: LinkErrorBlocked, Error: "URL is disallowed by robots.txt"}, true
+	}
+	if page.StatusCode == 0 {
+		return LinkStatus{}, false
+	}
+	status := LinkStatus{
+		StatusCode: page.StatusCode,
+		FinalURL:   page.FinalURL,
+		Redirected: len(page.RedirectChain) > 0,
+	}
+	if page.StatusCode >= 400 {
+		status.ErrorKind = LinkErrorHTTP
+		status.Error = http.StatusText(page.StatusCode)
+	}
+	return status, true
+}
@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckLinksRateLimitIsNotATimeout(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first check is pushed back for longer than the link timeout
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewCrawler("PhoenixSEO/1.0", 5*time.Second, 1)
+	c.SetCrawlDelay(0)
+	page := &CrawlResult{
+		URL:      srv.URL + "/",
+		FinalURL: srv.URL + "/",
+		LinkDetails: []Link{
+			{URL: srv.URL + "/a", Internal: true},
+			{URL: srv.URL + "/b", Internal: true},
+			{URL: srv.URL + "/c", Internal: true},
+		},
+	}
+
+	report, err := c.CheckLinks(context.Background(), []*CrawlResult{page}, LinkCheckOptions{Timeout: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("CheckLinks() error = %v", err)
+	}
+	if report.Checked != 3 {
+		t.Fatalf("Checked = %d, want 3: %+v", report.Checked, report.Results)
+	}
+	if broken := report.Broken(); len(broken) > 0 {
+		t.Errorf("links behind a 429 are reported as broken: %+v", broken)
+	}
+}
+
+func TestCheckLinksTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewCrawler("PhoenixSEO/1.0", 5*time.Second, 1)
+	c.SetCrawlDelay(0)
+	c.SetRespectRobotsTxt(false)
+	c.SetMaxRetries(0)
+	page := &CrawlResult{
+		URL:         srv.URL + "/",
+		FinalURL:    srv.URL + "/",
+		LinkDetails: []Link{{URL: srv.URL + "/slow", Internal: true}},
+	}
+
+	report, err := c.CheckLinks(context.Background(), []*CrawlResult{page}, LinkCheckOptions{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("CheckLinks() error = %v", err)
+	}
+	broken := report.Broken()
+	if len(broken) != 1 || broken[0].ErrorKind != LinkErrorTimeout {
+		t.Errorf("Broken() = %+v, want a single timeout", broken)
+	}
+}
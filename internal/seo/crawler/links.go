@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link is an <a href> element with the attributes that matter for SEO
+type Link struct {
+	URL       string
+	Anchor    string // visible text, the ALT text of an image link or the aria-label
+	Title     string
+	Rel       []string
+	Nofollow  bool
+	Sponsored bool
+	UGC       bool
+	Target    string
+	Region    string // main, header, nav, aside, footer or empty
+	ImageLink bool
+	Internal  bool
+}
+
+// addLink records a link in both the plain URL list and the detailed link list
+func (c *Crawler) addLink(n *html.Node, result *CrawlResult, baseURL *url.URL) {
+	href := strings.TrimSpace(c.getAttr(n, "href"))
+	if href == "" {
+		return
+	}
+	absURL := c.makeAbsolute(href, baseURL)
+	if absURL == "" {
+		return
+	}
+	result.Links = append(result.Links, absURL)
+
+	link := Link{
+		URL:    absURL,
+		Anchor: strings.Join(strings.Fields(c.extractText(n)), " "),
+		Title:  strings.TrimSpace(c.getAttr(n, "title")),
+		Target: strings.TrimSpace(c.getAttr(n, "target")),
+		Region: c.region(n),
+	}
+	if link.Anchor == "" && c.containsImage(n) {
+		link.ImageLink = true
+		link.Anchor = c.imageAltText(n)
+	}
+	if link.Anchor == "" {
+		link.Anchor = strings.TrimSpace(c.getAttr(n, "aria-label"))
+	}
+
+	for _, rel := range strings.Fields(strings.ToLower(c.getAttr(n, "rel"))) {
+		link.Rel = append(link.Rel, rel)
+		switch rel {
+		case "nofollow":
+			link.Nofollow = true
+		case "sponsored":
+			link.Sponsored = true
+		case "ugc":
+			link.UGC = true
+		}
+	}
+
+	if u, err := url.Parse(absURL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		link.Internal = newScopeChecker(CrawlScope{}, baseURL).isInternalHost(strings.ToLower(u.Host))
+	}
+
+	result.LinkDetails = append(result.LinkDetails, link)
+}
+
+// containsImage reports whether an element contains an <img>
+func (c *Crawler) containsImage(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.Data == "img" || c.containsImage(child)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFollowed reports whether search engines are asked to follow the link
+func (l Link) IsFollowed() bool {
+	return !l.Nofollow && !l.Sponsored && !l.UGC
+}
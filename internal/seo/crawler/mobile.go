@@ -0,0 +1,274 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// minFontSizePx is the smallest font size that is legible on a phone
+// without zooming
+const minFontSizePx = 12
+
+// maxMobileWidthPx is the width above which a fixed-width element no
+// longer fits on a phone screen
+const maxMobileWidthPx = 480
+
+// mobileParityRatio is the share of the desktop words and links a mobile
+// response needs to count as equivalent
+const mobileParityRatio = 0.7
+
+// Viewport is the parsed <meta name="viewport"> declaration
+type Viewport struct {
+	Content      string
+	Width        string // device-width or a pixel value
+	InitialScale float64
+	MaximumScale float64
+	UserScalable string
+}
+
+// StyleFinding is an inline CSS declaration that causes problems on small screens
+type StyleFinding struct {
+	Element     string
+	Declaration string
+}
+
+// MobileParity compares the response for a smartphone user agent with the
+// desktop response of the same URL
+type MobileParity struct {
+	UserAgent      string
+	StatusCode     int
+	FinalURL       string
+	Title          string
+	WordCount      int
+	H1Tags         []string
+	Links          int
+	Images         int
+	StructuredData int
+	CanonicalURL   string
+	Noindex        bool
+	// Differences lists the content differences to the desktop page
+	Differences []string
+	Error       string
+}
+
+// Declared reports whether the page has a viewport meta tag
+func (v Viewport) Declared() bool {
+	return v.Content != ""
+}
+
+// AdaptsToDevice reports whether the layout viewport follows the device width
+func (v Viewport) AdaptsToDevice() bool {
+	return v.Width == "device-width" || (v.Width == "" && v.InitialScale > 0)
+}
+
+// FixedWidth returns the pixel width of a fixed-width viewport, or 0
+func (v Viewport) FixedWidth() int {
+	width, err := strconv.Atoi(strings.TrimSuffix(v.Width, "px"))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// ZoomDisabled reports whether the viewport prevents users from zooming
+func (v Viewport) ZoomDisabled() bool {
+	if v.UserScalable == "no" || v.UserScalable == "0" {
+		return true
+	}
+	return v.MaximumScale > 0 && v.MaximumScale <= 1
+}
+
+// parseViewport parses the content of a viewport meta tag, e.g.
+// "width=device-width, initial-scale=1"
+func parseViewport(content string) Viewport {
+	v := Viewport{Content: strings.TrimSpace(content)}
+	for _, part := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, val, _ := strings.Cut(part, "=")
+		val = strings.ToLower(strings.TrimSpace(val))
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "width":
+			v.Width = val
+		case "initial-scale":
+			v.InitialScale, _ = strconv.ParseFloat(val, 64)
+		case "maximum-scale":
+			v.MaximumScale, _ = strconv.ParseFloat(val, 64)
+		case "user-scalable":
+			v.UserScalable = val
+		}
+	}
+	return v
+}
+
+// checkInlineStyle records tiny fonts and fixed widths declared in the
+// style attribute of an element
+func (r *CrawlResult) checkInlineStyle(element, style string) {
+	for _, decl := range strings.Split(style, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		finding := StyleFinding{Element: element, Declaration: prop + ": " + value}
+
+		switch prop {
+		case "font-size":
+			if px, ok := cssPixels(value); ok && px < minFontSizePx {
+				r.SmallFonts = append(r.SmallFonts, finding)
+			}
+		case "font":
+			// The size is the first length of the shorthand, e.g. "italic 10px/1.2 Arial"
+			for _, token := range strings.Fields(value) {
+				size, _, _ := strings.Cut(token, "/")
+				if px, ok := cssPixels(size); ok {
+					if px < minFontSizePx {
+						r.SmallFonts = append(r.SmallFonts, finding)
+					}
+					break
+				}
+			}
+		case "width", "min-width":
+			if px, ok := cssPixels(value); ok && px > maxMobileWidthPx && !strings.HasSuffix(value, "%") {
+				r.FixedWidths = append(r.FixedWidths, finding)
+			}
+		}
+	}
+}
+
+// cssPixels converts a CSS length or font-size keyword to pixels, assuming
+// the default root font size of 16px
+func cssPixels(value string) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "xx-small":
+		return 9, true
+	case "x-small":
+		return 10, true
+	case "small":
+		return 13, true
+	}
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{{"px", 1}, {"pt", 4.0 / 3}, {"rem", 16}, {"em", 16}, {"%", 0.16}}
+	for _, unit := range units {
+		if !strings.HasSuffix(value, unit.suffix) {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSuffix(value, unit.suffix), 64)
+		if err != nil {
+			return 0, false
+		}
+		return n * unit.factor, true
+	}
+	return 0, false
+}
+
+// checkMobileFriendly reports whether the page adapts to the screen width:
+// it needs a device-width viewport and must not force a fixed-width layout
+func (c *Crawler) checkMobileFriendly(result *CrawlResult) bool {
+	return result.Viewport.AdaptsToDevice() && len(result.FixedWidths) == 0
+}
+
+// mobileUserAgent is the smartphone user agent used for parity checks
+func (c *Crawler) mobileUserAgent() string {
+	return fmt.Sprintf("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 (compatible; %s)", c.userAgent)
+}
+
+// checkMobileParity fetches the page again with a smartphone user agent and
+// compares the content with the desktop response
+func (c *Crawler) checkMobileParity(ctx context.Context, desktop *CrawlResult) {
+	parity := &MobileParity{UserAgent: c.mobileUserAgent()}
+	desktop.MobileParity = parity
+
+	target, err := url.Parse(desktop.URL)
+	if err != nil {
+		parity.Error = fmt.Sprintf("invalid URL: %v", err)
+		return
+	}
+
+	mobile := &CrawlResult{URL: desktop.URL, FinalURL: desktop.URL, Headers: make(http.Header)}
+	resp, err := c.fetchPage(ctx, target, mobile, parity.UserAgent)
+	if err != nil {
+		parity.Error = err.Error()
+		return
+	}
+	if resp == nil {
+		parity.StatusCode = mobile.StatusCode
+		parity.FinalURL = mobile.FinalURL
+		parity.Error = strings.Join(mobile.Errors, "; ")
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := readPageBody(resp, mobile)
+	if err != nil {
+		parity.Error = err.Error()
+		return
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		parity.Error = fmt.Sprintf("failed to parse HTML: %v", err)
+		return
+	}
+	mobile.FinalURL = resp.Request.URL.String()
+	mobile.StatusCode = resp.StatusCode
+	mobile.parseXRobotsTag(resp.Header)
+	c.parseNode(doc, mobile, resp.Request.URL)
+
+	parity.StatusCode = mobile.StatusCode
+	parity.FinalURL = mobile.FinalURL
+	parity.Title = mobile.Title
+	parity.WordCount = mobile.WordCount
+	parity.H1Tags = mobile.H1Tags
+	parity.Links = len(mobile.LinkDetails)
+	parity.Images = len(mobile.Images)
+	parity.StructuredData = len(c.extractStructuredData(doc, resp.Request.URL))
+	parity.CanonicalURL = mobile.CanonicalURL
+	parity.Noindex = mobile.IsNoindex()
+	parity.Differences = compareMobile(desktop, mobile, parity)
+}
+
+// compareMobile lists the differences between the desktop and mobile versions
+func compareMobile(desktop, mobile *CrawlResult, parity *MobileParity) []string {
+	var diffs []string
+	if mobile.StatusCode != desktop.StatusCode {
+		diffs = append(diffs, fmt.Sprintf("status code %d on mobile, %d on desktop", mobile.StatusCode, desktop.StatusCode))
+	}
+	if mobile.FinalURL != desktop.FinalURL {
+		diffs = append(diffs, fmt.Sprintf("mobile is served from %s instead of %s", mobile.FinalURL, desktop.FinalURL))
+	}
+	if parity.Noindex && !desktop.IsNoindex() {
+		diffs = append(diffs, "mobile version is noindex")
+	}
+	if mobile.CanonicalURL != desktop.CanonicalURL {
+		diffs = append(diffs, fmt.Sprintf("canonical %q on mobile, %q on desktop", mobile.CanonicalURL, desktop.CanonicalURL))
+	}
+	if mobile.Title != desktop.Title {
+		diffs = append(diffs, fmt.Sprintf("title %q on mobile, %q on desktop", mobile.Title, desktop.Title))
+	}
+	if strings.Join(mobile.H1Tags, "|") != strings.Join(desktop.H1Tags, "|") {
+		diffs = append(diffs, "H1 headings differ")
+	}
+	if float64(mobile.WordCount) < float64(desktop.WordCount)*mobileParityRatio {
+		diffs = append(diffs, fmt.Sprintf("%d words on mobile, %d on desktop", mobile.WordCount, desktop.WordCount))
+	}
+	if float64(len(mobile.LinkDetails)) < float64(len(desktop.LinkDetails))*mobileParityRatio {
+		diffs = append(diffs, fmt.Sprintf("%d links on mobile, %d on desktop", len(mobile.LinkDetails), len(desktop.LinkDetails)))
+	}
+	if len(mobile.Images) < len(desktop.Images) {
+		diffs = append(diffs, fmt.Sprintf("%d images on mobile, %d on desktop", len(mobile.Images), len(desktop.Images)))
+	}
+	if parity.StructuredData < len(desktop.StructuredData) {
+		diffs = append(diffs, fmt.Sprintf("%d structured data items on mobile, %d on desktop", parity.StructuredData, len(desktop.StructuredData)))
+	}
+	return diffs
+}
@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Trailing slash policies
+const (
+	TrailingSlashKeep  = "keep"
+	TrailingSlashStrip = "strip"
+	TrailingSlashAdd   = "add"
+)
+
+// DefaultTrackingParams are the query parameters removed when a normalizer
+// does not configure its own list. Entries ending in "*" match by prefix.
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "gbraid", "wbraid", "dclid", "fbclid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "igshid", "srsltid", "ref_src",
+}
+
+// URLNormalizer turns URLs into a canonical form so that trivially
+// different spellings of the same page are crawled only once. The zero
+// value lowercases scheme and host, removes default ports and fragments and
+// strips DefaultTrackingParams.
+type URLNormalizer struct {
+	// TrackingParams are removed from the query; nil uses DefaultTrackingParams,
+	// an empty slice keeps all parameters
+	TrackingParams []string
+	// TrailingSlash is one of TrailingSlashKeep (default), TrailingSlashStrip or TrailingSlashAdd
+	TrailingSlash string
+	// HostAliases maps alternative host names to the host they should be treated as,
+	// e.g. "www.example.com" -> "example.com"
+	HostAliases map[string]string
+	// LowercasePath lowercases the path for servers with case-insensitive URLs
+	LowercasePath bool
+	// SortQuery orders query parameters alphabetically
+	SortQuery bool
+}
+
+// Normalize returns the normalized form of an absolute HTTP(S) URL
+func (n *URLNormalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("URL %q has no host", rawURL)
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+	u.Host = n.normalizeHost(u.Scheme, u.Host)
+
+	// Path
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if n.LowercasePath {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = ""
+	}
+	switch n.TrailingSlash {
+	case TrailingSlashStrip:
+		if len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+			u.Path = strings.TrimRight(u.Path, "/")
+			if u.Path == "" {
+				u.Path = "/"
+			}
+			u.RawPath = ""
+		}
+	case TrailingSlashAdd:
+		// Leave file-like paths such as /feed.xml alone
+		last := u.Path[strings.LastIndex(u.Path, "/")+1:]
+		if !strings.HasSuffix(u.Path, "/") && !strings.Contains(last, ".") {
+			u.Path += "/"
+			u.RawPath = ""
+		}
+	}
+
+	// Query
+	u.ForceQuery = false
+	if u.RawQuery != "" {
+		u.RawQuery = n.normalizeQuery(u.RawQuery)
+	}
+
+	return u.String(), nil
+}
+
+// normalizeHost lowercases the host, removes the default port and resolves aliases
+func (n *URLNormalizer) normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+			host = h
+			if strings.Contains(h, ":") {
+				host = "[" + h + "]"
+			}
+		}
+	}
+	host = strings.TrimSuffix(host, ".")
+	if alias, ok := n.HostAliases[host]; ok {
+		host = strings.ToLower(alias)
+	}
+	return host
+}
+
+// normalizeQuery removes tracking parameters and optionally sorts the query
+func (n *URLNormalizer) normalizeQuery(rawQuery string) string {
+	tracking := n.TrackingParams
+	if tracking == nil {
+		tracking = DefaultTrackingParams
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if !isTrackingParam(strings.ToLower(name), tracking) {
+			kept = append(kept, pair)
+		}
+	}
+	if n.SortQuery {
+		sort.Strings(kept)
+	}
+	return strings.Join(kept, "&")
+}
+
+// isTrackingParam reports whether a parameter name matches one of the patterns
+func isTrackingParam(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is the number of times a request is repeated after a
+	// 429, a 503 or a transient network error
+	defaultMaxRetries = 3
+	// minBackoff is the first pause after a host pushed back
+	minBackoff = 2 * time.Second
+	// maxBackoff caps the exponential backoff and the Retry-After that is
+	// waited for. Longer Retry-After values are not retried.
+	maxBackoff = 2 * time.Minute
+)
+
+// hostRateLimiter is a token bucket per host. The bucket holds a single
+// token that refills after the crawl delay; it is kept as the time the next
+// token is available, so concurrent workers reserve consecutive slots. After
+// a host pushes back, its requests are paused and spaced by a backoff that
+// doubles with every further pushback and halves with every successful
+// response.
+type hostRateLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostBucket
+}
+
+// hostBucket is the rate limiting state of a host
+type hostBucket struct {
+	next    time.Time
+	backoff time.Duration
+	// pausedUntil delays all requests after a 429 or 503
+	pausedUntil time.Time
+}
+
+// SetMaxRetries sets how often a page request is repeated after a 429, a 503
+// or a transient network error. 0 disables retries.
+func (c *Crawler) SetMaxRetries(n int) {
+	if n >= 0 {
+		c.maxRetries = n
+	}
+}
+
+// bucket returns the state of a host; the caller holds mu
+func (l *hostRateLimiter) bucket(host string) *hostBucket {
+	if l.hosts == nil {
+		l.hosts = make(map[string]*hostBucket)
+	}
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &hostBucket{}
+		l.hosts[host] = b
+	}
+	return b
+}
+
+// wait blocks until a request to host may be sent. interval is the minimum
+// time between two requests; a backoff of the host takes precedence if it is
+// longer. It returns early with the context's error.
+func (l *hostRateLimiter) wait(ctx context.Context, host string, interval time.Duration) error {
+	l.mu.Lock()
+	now := time.Now()
+	b := l.bucket(host)
+	if b.backoff > interval {
+		interval = b.backoff
+	}
+	slot := now
+	if b.next.After(slot) {
+		slot = b.next
+	}
+	if b.pausedUntil.After(slot) {
+		slot = b.pausedUntil
+	}
+	b.next = slot.Add(interval)
+	l.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand back the slot unless a later request reserved the next one
+		l.mu.Lock()
+		if b.next.Equal(slot.Add(interval)) {
+			b.next = slot
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// pushback records a 429, a 503 or a network error of a host and pauses it
+// for the longer of the backoff and retryAfter. It returns the pause.
+func (l *hostRateLimiter) pushback(host string, retryAfter time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b := l.bucket(host)
+	if b.backoff < minBackoff {
+		b.backoff = minBackoff
+	} else {
+		b.backoff = min(2*b.backoff, maxBackoff)
+	}
+	pause := max(b.backoff, min(retryAfter, maxBackoff))
+	if until := now.Add(pause); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	return pause
+}
+
+// success records a response that was not a pushback and relaxes the
+// backoff of the host
+func (l *hostRateLimiter) success(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(host)
+	b.backoff /= 2
+	if b.backoff < minBackoff {
+		b.backoff = 0
+	}
+}
+
+// sendPageRequest sends a page request without following redirects
+func (c *Crawler) sendPageRequest(ctx context.Context, req *http.Request, robotsDelay time.Duration) (*http.Response, time.Duration, error) {
+	return c.sendRequest(ctx, c.pageClient, req, robotsDelay)
+}
+
+// sendCheckRequest sends a request for an asset, an image or a check of a
+// crawled host, spaced by the Crawl-delay of the host's robots.txt.
+// Redirects are followed.
+func (c *Crawler) sendCheckRequest(ctx context.Context, req *http.Request) (*http.Response, time.Duration, error) {
+	return c.sendRequest(ctx, c.client, req, c.robotsDelay(ctx, req.URL))
+}
+
+// robotsDelay returns the Crawl-delay of the robots.txt of u's host, or 0
+// if robots.txt is not respected
+func (c *Crawler) robotsDelay(ctx context.Context, u *url.URL) time.Duration {
+	if !c.respectRobotsTxt {
+		return 0
+	}
+	robots, err := c.robotsFor(ctx, u)
+	if err != nil {
+		return 0
+	}
+	return robots.CrawlDelay(c.userAgent)
+}
+
+// sendRequest sends a request once the rate limit of its host allows it.
+// 429 and 503 responses and transient network errors are retried with
+// backoff. The returned duration is the latency of the last attempt. Every
+// request to a crawled host goes through here, so pages, assets and checks
+// share the budget of the host. The client's Timeout only starts once the
+// host's slot is reached, so waiting for the host never times a request out.
+func (c *Crawler) sendRequest(ctx context.Context, client *http.Client, req *http.Request, robotsDelay time.Duration) (*http.Response, time.Duration, error) {
+	host := req.URL.Host
+	interval := max(c.crawlDelay, robotsDelay)
+
+	for attempt := 0; ; attempt++ {
+		if err := c.rateLimiter.wait(ctx, host, interval); err != nil {
+			return nil, 0, err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		latency := time.Since(start)
+		if err != nil {
+			if ctx.Err() != nil || !isTransientError(err) {
+				return nil, latency, err
+			}
+			c.rateLimiter.pushback(host, 0)
+			if attempt >= c.maxRetries {
+				return nil, latency, err
+			}
+			continue
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			c.rateLimiter.success(host)
+			return resp, latency, nil
+		}
+
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		c.rateLimiter.pushback(host, retryAfter)
+		if attempt >= c.maxRetries || (ok && retryAfter > maxBackoff) {
+			return resp, latency, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// isTransientError reports whether a request error may go away when the
+// request is repeated: timeouts, reset or refused connections and
+// connections closed before the response
+func isTransientError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,226 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "120", 2 * time.Minute, true},
+		{"zero seconds", "0", 0, true},
+		{"negative seconds", "-5", 0, false},
+		{"fractional seconds", "1.5", 0, false},
+		{"HTTP date", "Fri, 01 Mar 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"RFC 850 date", "Friday, 01-Mar-24 12:01:00 GMT", time.Minute, true},
+		{"asctime date", "Fri Mar  1 12:00:10 2024", 10 * time.Second, true},
+		{"date in the past", "Fri, 01 Mar 2024 11:00:00 GMT", 0, true},
+		{"garbage", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHostRateLimiterPushback(t *testing.T) {
+	tests := []struct {
+		name        string
+		retryAfters []time.Duration
+		wantPauses  []time.Duration
+	}{
+		{
+			name:        "backoff starts at the minimum and doubles",
+			retryAfters: []time.Duration{0, 0, 0},
+			wantPauses:  []time.Duration{minBackoff, 2 * minBackoff, 4 * minBackoff},
+		},
+		{
+			name:        "backoff is capped",
+			retryAfters: []time.Duration{0, 0, 0, 0, 0, 0, 0, 0},
+			wantPauses:  []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, 64 * time.Second, maxBackoff, maxBackoff},
+		},
+		{
+			name:        "longer Retry-After wins",
+			retryAfters: []time.Duration{30 * time.Second, 0},
+			wantPauses:  []time.Duration{30 * time.Second, 2 * minBackoff},
+		},
+		{
+			name:        "shorter Retry-After does not shorten the backoff",
+			retryAfters: []time.Duration{time.Second},
+			wantPauses:  []time.Duration{minBackoff},
+		},
+		{
+			name:        "Retry-After is capped",
+			retryAfters: []time.Duration{time.Hour},
+			wantPauses:  []time.Duration{maxBackoff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l hostRateLimiter
+			for i, retryAfter := range tt.retryAfters {
+				before := time.Now()
+				pause := l.pushback("example.com", retryAfter)
+				if pause != tt.wantPauses[i] {
+					t.Errorf("pushback #%d = %v, want %v", i+1, pause, tt.wantPauses[i])
+				}
+				if until := l.hosts["example.com"].pausedUntil; until.Before(before.Add(pause)) {
+					t.Errorf("pushback #%d paused until %v, want at least %v", i+1, until, before.Add(pause))
+				}
+			}
+			if other := l.hosts["other.com"]; other != nil {
+				t.Errorf("pushback affected another host: %+v", other)
+			}
+		})
+	}
+}
+
+func TestHostRateLimiterSuccess(t *testing.T) {
+	tests := []struct {
+		backoff time.Duration
+		want    time.Duration
+	}{
+		{0, 0},
+		{minBackoff, 0},
+		{2 * minBackoff, minBackoff},
+		{8 * time.Second, 4 * time.Second},
+		{maxBackoff, maxBackoff / 2},
+	}
+
+	for _, tt := range tests {
+		var l hostRateLimiter
+		l.bucket("example.com").backoff = tt.backoff
+		l.success("example.com")
+		if got := l.hosts["example.com"].backoff; got != tt.want {
+			t.Errorf("success() with backoff %v = %v, want %v", tt.backoff, got, tt.want)
+		}
+	}
+}
+
+func TestHostRateLimiterWait(t *testing.T) {
+	const interval = 40 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// setup prepares the state of example.com before the measured wait
+		setup       func(l *hostRateLimiter)
+		interval    time.Duration
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		{
+			name:        "first request is not delayed",
+			setup:       func(l *hostRateLimiter) {},
+			interval:    interval,
+			maxDuration: interval / 2,
+		},
+		{
+			name: "second request waits for the interval",
+			setup: func(l *hostRateLimiter) {
+				l.wait(context.Background(), "example.com", interval)
+			},
+			interval:    interval,
+			minDuration: interval - 5*time.Millisecond,
+		},
+		{
+			name: "other hosts are not delayed",
+			setup: func(l *hostRateLimiter) {
+				l.wait(context.Background(), "other.com", time.Hour)
+			},
+			interval:    interval,
+			maxDuration: interval / 2,
+		},
+		{
+			name: "backoff replaces a shorter interval",
+			setup: func(l *hostRateLimiter) {
+				l.bucket("example.com").backoff = interval
+				l.wait(context.Background(), "example.com", 0)
+			},
+			interval:    0,
+			minDuration: interval - 5*time.Millisecond,
+		},
+		{
+			name: "pause delays the next request",
+			setup: func(l *hostRateLimiter) {
+				l.bucket("example.com").pausedUntil = time.Now().Add(interval)
+			},
+			interval:    0,
+			minDuration: interval - 5*time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l hostRateLimiter
+			tt.setup(&l)
+			start := time.Now()
+			if err := l.wait(context.Background(), "example.com", tt.interval); err != nil {
+				t.Fatalf("wait() error = %v", err)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.minDuration {
+				t.Errorf("wait() took %v, want at least %v", elapsed, tt.minDuration)
+			}
+			if tt.maxDuration > 0 && elapsed > tt.maxDuration {
+				t.Errorf("wait() took %v, want at most %v", elapsed, tt.maxDuration)
+			}
+		})
+	}
+}
+
+func TestHostRateLimiterWaitCanceled(t *testing.T) {
+	var l hostRateLimiter
+	l.wait(context.Background(), "example.com", time.Hour)
+	slot := l.hosts["example.com"].next
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, "example.com", time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if next := l.hosts["example.com"].next; !next.Equal(slot) {
+		t.Errorf("canceled wait kept its slot: next = %v, want %v", next, slot)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"timeout", context.DeadlineExceeded, true},
+		{"DNS timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"unknown host", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"other error", errors.New("certificate signed by unknown authority"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransientError(tt.err); got != tt.want {
+			t.Errorf("%s: isTransientError() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
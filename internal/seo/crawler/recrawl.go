@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// Page change states of a re-crawl
+const (
+	// ChangeNew is a page that was not part of the previous crawl
+	ChangeNew = "new"
+	// ChangeNotModified is a page the server answered with 304 Not Modified
+	ChangeNotModified = "not_modified"
+	// ChangeUnchanged is a page whose body is byte-identical to the previous crawl
+	ChangeUnchanged = "unchanged"
+	// ChangeChanged is a page whose body changed
+	ChangeChanged = "changed"
+)
+
+// PageChange describes how a page changed since the previous crawl
+type PageChange struct {
+	Status string
+	// Fields lists the SEO-relevant fields that differ, e.g. title or
+	// canonical. A changed page with no fields only changed in markup.
+	Fields []string
+}
+
+// CrawlChanges summarizes the changes of a site crawl against a previous crawl
+type CrawlChanges struct {
+	PreviousCrawlID string
+	New             []string
+	Changed         []string
+	Unchanged       int
+	// Removed are pages of the previous crawl that a complete re-crawl did
+	// not reach. It is only filled if the frontier was fully drained.
+	Removed []string
+	// NotRecrawled are pages of the previous crawl that were not crawled
+	// again because the re-crawl stopped at MaxPages or was cancelled, or
+	// because robots.txt now disallows them
+	NotRecrawled []string
+}
+
+// RecrawlPage crawls a page again. The validators of the previous result
+// are sent as a conditional request; if the server answers 304 or returns
+// the same body, the previous result is reused without parsing and only its
+// assets are measured again. The returned result describes the change in
+// Change.
+func (c *Crawler) RecrawlPage(ctx context.Context, urlStr string, previous *CrawlResult) (*CrawlResult, error) {
+	return c.crawlPage(ctx, &crawlCache{}, urlStr, previous)
+}
+
+// bodyHash returns the hex SHA-256 of a response body
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// reusePage returns the previous result of a page that did not change and
+// measures its assets again: the HTML is the same, but the stylesheets,
+// scripts and images it references may have been replaced since
+func (c *Crawler) reusePage(ctx context.Context, cache *crawlCache, result, previous *CrawlResult, status string) *CrawlResult {
+	reused := result.reuse(previous, status)
+	if c.checkAssets && len(reused.Assets) > 0 {
+		// The slices are shared with the previous result
+		reused.Assets = slices.Clone(reused.Assets)
+		reused.Images = slices.Clone(reused.Images)
+		c.fetchAssetRange(ctx, cache, reused.Assets)
+		reused.applyImageChecks()
+	}
+	reused.Weight = reused.computeWeight()
+	return reused
+}
+
+// reuse returns a copy of the previous result of a page with the request
+// data of r, which holds the redirects and response of the re-crawl
+func (r *CrawlResult) reuse(previous *CrawlResult, status string) *CrawlResult {
+	reused := *previous
+	reused.URL = r.URL
+	reused.RedirectChain = r.RedirectChain
+	reused.LoadTimeMs = r.LoadTimeMs
+	if status == ChangeNotModified {
+		// A 304 has no body, the size of the page is the previous one
+		reused.Transfer.TTFBMs = r.Transfer.TTFBMs
+		reused.Transfer.DownloadMs = 0
+	} else {
+		reused.Transfer = r.Transfer
+		reused.Headers = r.Headers
+		reused.TLS = r.TLS
+		reused.Cookies = r.Cookies
+	}
+	reused.Change = &PageChange{Status: status}
+	reused.previous = nil
+	return &reused
+}
+
+// compareCrawls sets the change of a freshly parsed page against its
+// previous result
+func (r *CrawlResult) compareCrawls(previous *CrawlResult) {
+	if previous == nil {
+		return
+	}
+	change := &PageChange{Status: ChangeChanged}
+	r.Change = change
+
+	differs := func(field string, changed bool) {
+		if changed {
+			change.Fields = append(change.Fields, field)
+		}
+	}
+	differs("status_code", r.StatusCode != previous.StatusCode)
+	differs("final_url", r.FinalURL != previous.FinalURL)
+	differs("title", r.Title != previous.Title)
+	differs("meta_description", r.MetaDescription != previous.MetaDescription)
+	differs("h1", !slices.Equal(r.H1Tags, previous.H1Tags))
+	differs("canonical", r.CanonicalURL != previous.CanonicalURL)
+	differs("indexability", r.Indexability != previous.Indexability)
+	differs("content", r.Content.Hash != previous.Content.Hash)
+	differs("links", !slices.Equal(r.Links, previous.Links))
+	differs("structured_data", len(r.StructuredData) != len(previous.StructuredData))
+	differs("hreflang", !slices.Equal(r.Hreflang, previous.Hreflang))
+}
+
+// compareUnfetched sets the change of a page whose redirect chain ended
+// without a response, e.g. because robots.txt blocks it or it redirects in
+// a loop. There is no body to compare, so it is unchanged unless its status,
+// target or indexability differ.
+func (r *CrawlResult) compareUnfetched(previous *CrawlResult) {
+	r.compareCrawls(previous)
+	if r.Change != nil && len(r.Change.Fields) == 0 {
+		r.Change.Status = ChangeUnchanged
+	}
+}
+
+// loadPreviousCrawl reads the pages of a checkpointed crawl by URL. URLs
+// that were blocked by robots.txt were not pages of that crawl.
+func (c *Crawler) loadPreviousCrawl(crawlID string, normalizer *URLNormalizer) (map[string]*CrawlResult, error) {
+	if c.checkpointDir == "" {
+		return nil, fmt.Errorf("re-crawling requires a checkpoint directory")
+	}
+	if !crawlIDPattern.MatchString(crawlID) {
+		return nil, fmt.Errorf("invalid crawl ID %q", crawlID)
+	}
+	records, _, err := readCheckpointRecords(filepath.Join(c.checkpointDir, crawlID, checkpointRecordsFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no pages found for crawl %s", crawlID)
+	}
+
+	pages := make(map[string]*CrawlResult, len(records))
+	for _, record := range records {
+		if record.Result == nil || isBlockedURL(record.Result) {
+			continue
+		}
+		key := record.Item.URL
+		if normalized, err := normalizer.Normalize(key); err == nil {
+			key = normalized
+		}
+		pages[key] = record.Result
+	}
+	return pages, nil
+}
+
+// summarizeChanges compares the pages of a re-crawl with the previous crawl.
+// blocked are the URLs of the re-crawl that robots.txt disallowed. finished
+// reports whether the frontier was fully drained; only then are previous
+// pages that were not reached reported as removed.
+func summarizeChanges(crawlID string, pages, blocked []*CrawlResult, previous map[string]*CrawlResult, normalizer *URLNormalizer, finished bool) *CrawlChanges {
+	changes := &CrawlChanges{PreviousCrawlID: crawlID}
+	normalize := func(urlStr string) string {
+		if normalized, err := normalizer.Normalize(urlStr); err == nil {
+			return normalized
+		}
+		return urlStr
+	}
+	crawled := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		crawled[normalize(page.URL)] = true
+
+		switch {
+		case page.Change == nil || page.Change.Status == ChangeNew:
+			changes.New = append(changes.New, page.URL)
+		case page.Change.Status == ChangeChanged:
+			changes.Changed = append(changes.Changed, page.URL)
+		default:
+			changes.Unchanged++
+		}
+	}
+	disallowed := make(map[string]bool, len(blocked))
+	for _, page := range blocked {
+		disallowed[normalize(page.URL)] = true
+	}
+	for key := range previous {
+		switch {
+		case crawled[key]:
+			// Compared above
+		case finished && !disallowed[key]:
+			changes.Removed = append(changes.Removed, key)
+		default:
+			changes.NotRecrawled = append(changes.NotRecrawled, key)
+		}
+	}
+	slices.Sort(changes.Removed)
+	slices.Sort(changes.NotRecrawled)
+	return changes
+}
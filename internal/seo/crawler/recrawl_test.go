@@ -0,0 +1,196 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// recrawlSite is a test site whose pages can change between crawls
+type recrawlSite struct {
+	srv *httptest.Server
+	// version changes the body of /b
+	version atomic.Int32
+	// dropB removes /b and the link to it
+	dropB atomic.Bool
+	// notModified counts the 304 responses for /a
+	notModified atomic.Int32
+}
+
+func newRecrawlSite(t *testing.T) *recrawlSite {
+	s := &recrawlSite{}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /admin"))
+		case "/":
+			links := []string{"/a", "/b", "/admin/x", "/loop"}
+			if s.dropB.Load() {
+				links = []string{"/a", "/admin/x", "/loop"}
+			}
+			w.Write([]byte(testPage("Home", links...)))
+		case "/a":
+			w.Header().Set("ETag", `"a1"`)
+			if r.Header.Get("If-None-Match") == `"a1"` {
+				s.notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte(testPage("A")))
+		case "/b":
+			if s.dropB.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			if s.version.Load() == 0 {
+				w.Write([]byte(testPage("B")))
+			} else {
+				w.Write([]byte(testPage("B, updated")))
+			}
+		case "/loop":
+			http.Redirect(w, r, "/loop2", http.StatusMovedPermanently)
+		case "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *recrawlSite) urls(paths ...string) []string {
+	urls := make([]string, len(paths))
+	for i, path := range paths {
+		urls[i] = s.srv.URL + path
+	}
+	return urls
+}
+
+func TestCrawlSiteRecrawl(t *testing.T) {
+	tests := []struct {
+		name string
+		// change modifies the site before the re-crawl
+		change           func(s *recrawlSite)
+		maxPages         int
+		wantNew          []string
+		wantChanged      []string
+		wantUnchanged    int
+		wantRemoved      []string
+		wantNotRecrawled []string
+	}{
+		{
+			name:          "nothing changed",
+			change:        func(s *recrawlSite) {},
+			wantUnchanged: 4,
+		},
+		{
+			name:          "changed page",
+			change:        func(s *recrawlSite) { s.version.Store(1) },
+			wantChanged:   []string{"/b"},
+			wantUnchanged: 3,
+		},
+		{
+			name:          "removed page",
+			change:        func(s *recrawlSite) { s.dropB.Store(true) },
+			wantChanged:   []string{"/"},
+			wantUnchanged: 2,
+			wantRemoved:   []string{"/b"},
+		},
+		{
+			name:             "page limit does not remove pages",
+			change:           func(s *recrawlSite) {},
+			maxPages:         1,
+			wantUnchanged:    1,
+			wantNotRecrawled: []string{"/a", "/b", "/loop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newRecrawlSite(t)
+			c := newTestCrawler()
+			c.SetCheckpointDir(t.TempDir())
+
+			if _, err := c.CrawlSite(context.Background(), site.srv.URL, SiteCrawlOptions{Concurrency: 1, CrawlID: "first"}); err != nil {
+				t.Fatalf("first crawl failed: %v", err)
+			}
+			tt.change(site)
+			result, err := c.CrawlSite(context.Background(), site.srv.URL, SiteCrawlOptions{
+				Concurrency:     1,
+				MaxPages:        tt.maxPages,
+				PreviousCrawlID: "first",
+			})
+			if err != nil {
+				t.Fatalf("re-crawl failed: %v", err)
+			}
+
+			changes := result.Changes
+			if changes == nil {
+				t.Fatal("re-crawl has no changes")
+			}
+			if !slices.Equal(changes.New, site.urls(tt.wantNew...)) {
+				t.Errorf("New = %v, want %v", changes.New, site.urls(tt.wantNew...))
+			}
+			if !slices.Equal(changes.Changed, site.urls(tt.wantChanged...)) {
+				t.Errorf("Changed = %v, want %v", changes.Changed, site.urls(tt.wantChanged...))
+			}
+			if changes.Unchanged != tt.wantUnchanged {
+				t.Errorf("Unchanged = %d, want %d", changes.Unchanged, tt.wantUnchanged)
+			}
+			if !slices.Equal(changes.Removed, site.urls(tt.wantRemoved...)) {
+				t.Errorf("Removed = %v, want %v", changes.Removed, site.urls(tt.wantRemoved...))
+			}
+			if !slices.Equal(changes.NotRecrawled, site.urls(tt.wantNotRecrawled...)) {
+				t.Errorf("NotRecrawled = %v, want %v", changes.NotRecrawled, site.urls(tt.wantNotRecrawled...))
+			}
+			if tt.maxPages == 0 && result.Skipped[SkipBlockedByRobots] != 1 {
+				t.Errorf("Skipped = %v, want the URL blocked by robots.txt", result.Skipped)
+			}
+		})
+	}
+}
+
+func TestCrawlSiteRecrawlNotModified(t *testing.T) {
+	site := newRecrawlSite(t)
+	c := newTestCrawler()
+	c.SetCheckpointDir(t.TempDir())
+
+	first, err := c.CrawlSite(context.Background(), site.srv.URL, SiteCrawlOptions{Concurrency: 1, CrawlID: "first"})
+	if err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+	second, err := c.CrawlSite(context.Background(), site.srv.URL, SiteCrawlOptions{Concurrency: 1, PreviousCrawlID: "first"})
+	if err != nil {
+		t.Fatalf("re-crawl failed: %v", err)
+	}
+	if site.notModified.Load() != 1 {
+		t.Errorf("/a answered 304 %d times, want 1", site.notModified.Load())
+	}
+
+	byURL := func(pages []*CrawlResult, path string) *CrawlResult {
+		for _, page := range pages {
+			if page.URL == site.srv.URL+path {
+				return page
+			}
+		}
+		t.Fatalf("%s was not crawled", path)
+		return nil
+	}
+	before, after := byURL(first.Pages, "/a"), byURL(second.Pages, "/a")
+	if after.Change == nil || after.Change.Status != ChangeNotModified {
+		t.Errorf("Change of /a = %+v, want %s", after.Change, ChangeNotModified)
+	}
+	if after.StatusCode != http.StatusOK || after.Title != before.Title || after.BodyHash != before.BodyHash {
+		t.Errorf("304 did not reuse the previous result: %+v", after)
+	}
+	if loop := byURL(second.Pages, "/loop"); loop.Change == nil || loop.Change.Status != ChangeUnchanged {
+		t.Errorf("Change of the redirect loop = %+v, want %s", loop.Change, ChangeUnchanged)
+	}
+	if home := byURL(second.Pages, "/"); home.Change == nil || home.Change.Status != ChangeUnchanged {
+		t.Errorf("Change of the home page = %+v, want %s", home.Change, ChangeUnchanged)
+	}
+}
@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// maxRedirects is the maximum number of redirect hops followed for a page
+const maxRedirects = 10
+
+// RedirectHop is a single redirect response on the way to the final page
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+	LatencyMs  int64
+}
+
+// fetchPage requests a page and follows redirects itself so that every hop
+// is recorded in result.RedirectChain. Each hop is checked against
+// robots.txt and the rate limit of its host. A nil response without an
+// error means the chain ended without a page (blocked, loop, too many hops
+// or a missing Location header); the reason is added to result.Errors.
+// userAgent is sent with the requests, robots.txt is always evaluated for
+// the crawler's own user agent.
+func (c *Crawler) fetchPage(ctx context.Context, target *url.URL, result *CrawlResult, userAgent string) (*http.Response, error) {
+	visited := make(map[string]bool)
+
+	for {
+		// Check robots.txt
+		var robots *RobotsTxt
+		if c.respectRobotsTxt {
+			var err error
+			robots, err = c.robotsFor(ctx, target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+			}
+			if !robots.IsAllowed(c.userAgent, target) {
+				result.BlockedByRobotsTxt = true
+				result.Errors = append(result.Errors, fmt.Sprintf("blocked by robots.txt: %s is disallowed for %s", target, robotsProductToken(c.userAgent)))
+				return nil, nil
+			}
+		}
+
+		req, err := c.newPageRequest(ctx, target.String(), userAgent)
+		if err != nil {
+			return nil, err
+		}
+		// Validators of a previous crawl apply to the page it ended at
+		if previous := result.previous; previous != nil && previous.FinalURL == target.String() {
+			if previous.Transfer.ETag != "" {
+				req.Header.Set("If-None-Match", previous.Transfer.ETag)
+			}
+			if previous.Transfer.LastModified != "" {
+				req.Header.Set("If-Modified-Since", previous.Transfer.LastModified)
+			}
+		}
+
+		visited[target.String()] = true
+		resp, latency, err := c.sendPageRequest(ctx, req, robots.CrawlDelay(c.userAgent))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		}
+		if !isRedirectStatus(resp.StatusCode) {
+			result.Transfer.TTFBMs = latency.Milliseconds()
+			return resp, nil
+		}
+
+		hop := RedirectHop{
+			URL:        target.String(),
+			StatusCode: resp.StatusCode,
+			LatencyMs:  latency.Milliseconds(),
+		}
+		location := resp.Header.Get("Location")
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+
+		result.StatusCode = resp.StatusCode
+		if location == "" {
+			result.RedirectChain = append(result.RedirectChain, hop)
+			result.Errors = append(result.Errors, fmt.Sprintf("redirect from %s has no Location header", target))
+			return nil, nil
+		}
+		next, err := target.Parse(location)
+		if err != nil {
+			result.RedirectChain = append(result.RedirectChain, hop)
+			result.Errors = append(result.Errors, fmt.Sprintf("redirect from %s has an invalid Location %q", target, location))
+			return nil, nil
+		}
+
+		hop.Location = next.String()
+		result.RedirectChain = append(result.RedirectChain, hop)
+		result.FinalURL = hop.Location
+
+		if visited[hop.Location] {
+			result.Errors = append(result.Errors, fmt.Sprintf("redirect loop detected at %s", hop.Location))
+			return nil, nil
+		}
+		if len(result.RedirectChain) >= maxRedirects {
+			result.Errors = append(result.Errors, fmt.Sprintf("too many redirects (%d)", len(result.RedirectChain)))
+			return nil, nil
+		}
+		target = next
+	}
+}
+
+// isRedirectStatus reports whether a status code is a followable redirect
+func isRedirectStatus(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// HasRedirectLoop reports whether the redirect chain points back to a URL
+// that was already part of it
+func (r *CrawlResult) HasRedirectLoop() bool {
+	seen := make(map[string]bool, len(r.RedirectChain))
+	for _, hop := range r.RedirectChain {
+		seen[hop.URL] = true
+		if seen[hop.Location] {
+			return true
+		}
+	}
+	return false
+}
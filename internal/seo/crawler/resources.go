@@ -0,0 +1,292 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Asset kinds
+const (
+	AssetStylesheet = "stylesheet"
+	AssetScript     = "script"
+	AssetImage      = "image"
+	AssetFont       = "font"
+	AssetIframe     = "iframe"
+)
+
+// cssURLPattern matches url(...) references in CSS
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+
+// fontExtensions are the file types of web fonts
+var fontExtensions = map[string]bool{
+	".woff2": true,
+	".woff":  true,
+	".ttf":   true,
+	".otf":   true,
+	".eot":   true,
+}
+
+// Asset is a subresource referenced by a page
+type Asset struct {
+	URL  string
+	Kind string
+	// Position is the index of the referencing element in document order
+	Position int
+	InHead   bool
+	Async    bool
+	Defer    bool
+	Module   bool
+	Preload  bool
+	Lazy     bool
+	Media    string
+	// RenderBlocking is set for stylesheets and synchronous scripts in <head>
+	RenderBlocking bool
+	StatusCode     int
+	Transfer       Transfer
+	// Width and Height are the intrinsic dimensions of images
+	Width  int
+	Height int
+	Error  string
+
+	// fonts are the web fonts referenced by a stylesheet
+	fonts []string
+}
+
+// PageWeight summarizes the requests and bytes needed to load a page
+type PageWeight struct {
+	Requests       int
+	TotalBytes     int64
+	BytesByKind    map[string]int64
+	RenderBlocking int
+	// Unmeasured counts assets whose size is unknown because they were not
+	// fetched or failed
+	Unmeasured int
+}
+
+// collectAssets returns the subresources of a page in document order
+func (c *Crawler) collectAssets(doc *html.Node, baseURL *url.URL) []Asset {
+	var assets []Asset
+	index := make(map[string]int)
+	position := 0
+
+	add := func(ref string, asset Asset) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		abs := c.makeAbsolute(ref, baseURL)
+		if !strings.HasPrefix(abs, "http://") && !strings.HasPrefix(abs, "https://") {
+			return
+		}
+		asset.URL = abs
+		asset.Position = position
+
+		i, ok := index[abs]
+		if !ok {
+			if len(assets) >= maxAssetsPerPage {
+				return
+			}
+			index[abs] = len(assets)
+			assets = append(assets, asset)
+			return
+		}
+		// A preload hint followed by the actual reference describes one request
+		existing := &assets[i]
+		if existing.Preload && !asset.Preload {
+			asset.Position = existing.Position
+			asset.Preload = true
+			*existing = asset
+		}
+	}
+
+	var walk func(*html.Node, bool)
+	walk = func(n *html.Node, inHead bool) {
+		if n.Type == html.ElementNode {
+			position++
+			switch n.Data {
+			case "head":
+				inHead = true
+			case "link":
+				rel := c.getAttr(n, "rel")
+				switch {
+				case hasRel(rel, "stylesheet") && !c.hasAttr(n, "disabled"):
+					media := strings.ToLower(strings.TrimSpace(c.getAttr(n, "media")))
+					add(c.getAttr(n, "href"), Asset{
+						Kind:           AssetStylesheet,
+						InHead:         inHead,
+						Media:          media,
+						RenderBlocking: inHead && isBlockingMedia(media),
+					})
+				case hasRel(rel, "preload"), hasRel(rel, "modulepreload"):
+					kind := preloadKind(c.getAttr(n, "as"))
+					if hasRel(rel, "modulepreload") {
+						kind = AssetScript
+					}
+					if kind != "" {
+						add(c.getAttr(n, "href"), Asset{Kind: kind, InHead: inHead, Preload: true})
+					}
+				}
+			case "script":
+				if src := c.getAttr(n, "src"); src != "" {
+					async, deferred := c.hasAttr(n, "async"), c.hasAttr(n, "defer")
+					module := strings.EqualFold(c.getAttr(n, "type"), "module")
+					add(src, Asset{
+						Kind:           AssetScript,
+						InHead:         inHead,
+						Async:          async,
+						Defer:          deferred,
+						Module:         module,
+						RenderBlocking: inHead && !async && !deferred && !module,
+					})
+				}
+			case "img":
+				add(c.getAttr(n, "src"), Asset{
+					Kind:   AssetImage,
+					InHead: inHead,
+					Lazy:   strings.EqualFold(c.getAttr(n, "loading"), "lazy"),
+				})
+			case "iframe":
+				add(c.getAttr(n, "src"), Asset{
+					Kind: AssetIframe,
+					Lazy: strings.EqualFold(c.getAttr(n, "loading"), "lazy"),
+				})
+			case "style":
+				for _, font := range cssFontURLs(c.extractText(n), baseURL) {
+					add(font, Asset{Kind: AssetFont, InHead: inHead})
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inHead)
+		}
+	}
+	walk(doc, false)
+
+	return assets
+}
+
+// isBlockingMedia reports whether a stylesheet with the given media
+// attribute blocks rendering on screens. Media queries are assumed to match,
+// only print stylesheets load without blocking.
+func isBlockingMedia(media string) bool {
+	return !strings.Contains(media, "print") || strings.Contains(media, "screen") || strings.Contains(media, "all")
+}
+
+// preloadKind maps the "as" attribute of a preload hint to an asset kind
+func preloadKind(as string) string {
+	switch strings.ToLower(strings.TrimSpace(as)) {
+	case "style":
+		return AssetStylesheet
+	case "script":
+		return AssetScript
+	case "image":
+		return AssetImage
+	case "font":
+		return AssetFont
+	}
+	return ""
+}
+
+// cssFontURLs returns the web fonts referenced by a stylesheet
+func cssFontURLs(css string, baseURL *url.URL) []string {
+	var fonts []string
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		ref, err := baseURL.Parse(match[1])
+		if err != nil {
+			continue
+		}
+		if fontExtensions[strings.ToLower(path.Ext(ref.Path))] {
+			fonts = append(fonts, ref.String())
+		}
+	}
+	return fonts
+}
+
+// fetchAssets fetches the subresources of a page with a few parallel
+// requests, as a browser would. Fonts referenced by the fetched stylesheets
+// are added to the assets and fetched as well.
+func (c *Crawler) fetchAssets(ctx context.Context, cache *crawlCache, result *CrawlResult) {
+	c.fetchAssetRange(ctx, cache, result.Assets)
+
+	known := make(map[string]bool, len(result.Assets))
+	for _, asset := range result.Assets {
+		known[asset.URL] = true
+	}
+	first := len(result.Assets)
+	for i := 0; i < first; i++ {
+		sheet := result.Assets[i]
+		for _, font := range sheet.fonts {
+			if known[font] || len(result.Assets) >= maxAssetsPerPage {
+				continue
+			}
+			known[font] = true
+			result.Assets = append(result.Assets, Asset{
+				URL:      font,
+				Kind:     AssetFont,
+				Position: sheet.Position,
+				InHead:   sheet.InHead,
+			})
+		}
+	}
+	c.fetchAssetRange(ctx, cache, result.Assets[first:])
+}
+
+// fetchAssetRange fetches the given assets in parallel
+func (c *Crawler) fetchAssetRange(ctx context.Context, cache *crawlCache, assets []Asset) {
+	sem := make(chan struct{}, assetConcurrency)
+	var wg sync.WaitGroup
+	for i := range assets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(asset *Asset) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fetched := c.fetchAsset(ctx, &cache.assets, asset.URL, asset.Kind)
+			asset.StatusCode = fetched.StatusCode
+			asset.Transfer = fetched.Transfer
+			asset.Width = fetched.Width
+			asset.Height = fetched.Height
+			asset.Error = fetched.Error
+			asset.fonts = fetched.fonts
+		}(&assets[i])
+	}
+	wg.Wait()
+}
+
+// computeWeight sums the requests and transferred bytes of the page and its
+// subresources
+func (r *CrawlResult) computeWeight() PageWeight {
+	weight := PageWeight{
+		Requests:    1 + len(r.RedirectChain),
+		TotalBytes:  r.Transfer.TransferSize,
+		BytesByKind: map[string]int64{"document": r.Transfer.TransferSize},
+	}
+	for _, asset := range r.Assets {
+		if asset.RenderBlocking {
+			weight.RenderBlocking++
+		}
+		if asset.Lazy {
+			// Lazy resources are not part of the initial load
+			continue
+		}
+		weight.Requests++
+		if asset.StatusCode == 0 {
+			weight.Unmeasured++
+			continue
+		}
+		weight.TotalBytes += asset.Transfer.TransferSize
+		weight.BytesByKind[asset.Kind] += asset.Transfer.TransferSize
+	}
+	return weight
+}
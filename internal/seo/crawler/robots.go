@@ -0,0 +1,384 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRobotsTxtSize is the maximum robots.txt size that is parsed (Google uses 500 KiB)
+	maxRobotsTxtSize = 500 * 1024
+	// robotsTxtTTL is how long a fetched robots.txt is cached per host
+	robotsTxtTTL = 24 * time.Hour
+	// robotsTxtErrorTTL is how long a host stays disallowed after its
+	// robots.txt failed with a network error or a 5xx, so a brief outage
+	// does not lock the site out for a day
+	robotsTxtErrorTTL = 5 * time.Minute
+)
+
+// RobotsTxt represents a parsed robots.txt file
+type RobotsTxt struct {
+	Sitemaps []string
+	groups   []robotsGroup
+	// disallowAll is set when robots.txt could not be fetched
+	disallowAll bool
+}
+
+// robotsGroup is a set of rules that applies to one or more user agents
+type robotsGroup struct {
+	agents        []string
+	rules         []robotsRule
+	crawlDelay    time.Duration
+	hasCrawlDelay bool
+}
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobotsTxt parses the content of a robots.txt file
+func ParseRobotsTxt(data []byte) *RobotsTxt {
+	robots := &RobotsTxt{}
+	if len(data) > maxRobotsTxtSize {
+		data = data[:maxRobotsTxtSize]
+	}
+
+	var current *robotsGroup
+	inRules := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRobotsTxtSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent", "useragent", "user agent":
+			// Consecutive user-agent lines share one group; a user-agent
+			// line after rules starts a new group
+			if current == nil || inRules {
+				robots.groups = append(robots.groups, robotsGroup{})
+				current = &robots.groups[len(robots.groups)-1]
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// An empty Disallow means "allow everything" and carries no rule
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				pattern: normalizeRobotsPath(value),
+			})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				current.hasCrawlDelay = true
+			}
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+	}
+
+	return robots
+}
+
+// IsAllowed reports whether the given user agent may fetch the URL
+func (r *RobotsTxt) IsAllowed(userAgent string, u *url.URL) bool {
+	if r == nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	// robots.txt itself is always allowed
+	if path == "/robots.txt" {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	path = normalizeRobotsPath(path)
+
+	// The longest matching pattern wins; Allow wins a tie
+	matched := false
+	allowed := true
+	longest := -1
+	for _, rule := range r.rulesFor(userAgent) {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		length := len(rule.pattern)
+		if length > longest || (length == longest && rule.allow && !allowed) {
+			longest = length
+			allowed = rule.allow
+			matched = true
+		}
+	}
+
+	return !matched || allowed
+}
+
+// CrawlDelay returns the Crawl-delay that applies to the given user agent
+func (r *RobotsTxt) CrawlDelay(userAgent string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	for _, group := range r.groupsFor(userAgent) {
+		if group.hasCrawlDelay {
+			return group.crawlDelay
+		}
+	}
+	return 0
+}
+
+// rulesFor returns the merged rules of all groups that apply to the user agent
+func (r *RobotsTxt) rulesFor(userAgent string) []robotsRule {
+	var rules []robotsRule
+	for _, group := range r.groupsFor(userAgent) {
+		rules = append(rules, group.rules...)
+	}
+	return rules
+}
+
+// groupsFor returns the groups that name the crawler's product token;
+// groups for "*" are only used if no group names it. As RFC 9309 requires,
+// the whole token is compared case-insensitively, so a group for "phoenix"
+// does not apply to "phoenixseo".
+func (r *RobotsTxt) groupsFor(userAgent string) []*robotsGroup {
+	token := robotsProductToken(userAgent)
+
+	var matched []*robotsGroup
+	var wildcard []*robotsGroup
+
+	for i := range r.groups {
+		group := &r.groups[i]
+		if token != "" && slices.Contains(group.agents, token) {
+			matched = append(matched, group)
+		} else if slices.Contains(group.agents, "*") {
+			wildcard = append(wildcard, group)
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// robotsProductToken extracts the lowercase product token from a User-Agent,
+// e.g. "phoenixseo" from "PhoenixSEO/1.0 (+https://phoenix-seo.com/bot)"
+func robotsProductToken(userAgent string) string {
+	token := strings.TrimSpace(userAgent)
+	if idx := strings.IndexAny(token, "/ ;("); idx >= 0 {
+		token = token[:idx]
+	}
+	return strings.ToLower(token)
+}
+
+// matchRobotsPattern matches a path against a robots.txt pattern supporting
+// the "*" wildcard and the "$" end anchor
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
+
+// normalizeRobotsPath percent-encodes non-ASCII bytes and uppercases existing
+// escapes so that patterns and paths compare consistently
+func normalizeRobotsPath(path string) string {
+	const hex = "0123456789ABCDEF"
+	var buf strings.Builder
+	for i := 0; i < len(path); i++ {
+		b := path[i]
+		switch {
+		case b >= 0x80:
+			buf.WriteByte('%')
+			buf.WriteByte(hex[b>>4])
+			buf.WriteByte(hex[b&0x0F])
+		case b == '%' && i+2 < len(path):
+			buf.WriteByte('%')
+			buf.WriteString(strings.ToUpper(path[i+1 : i+3]))
+			i += 2
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return buf.String()
+}
+
+// robotsCache caches parsed robots.txt files per scheme and host
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+// robotsEntry is a cached robots.txt; ready is closed once robots is set
+type robotsEntry struct {
+	ready     chan struct{}
+	robots    *RobotsTxt
+	err       error
+	fetchedAt time.Time
+}
+
+// ttl returns how long the entry is used; the caller waited for ready
+func (e *robotsEntry) ttl() time.Duration {
+	if e.robots != nil && e.robots.disallowAll {
+		return robotsTxtErrorTTL
+	}
+	return robotsTxtTTL
+}
+
+// RobotsTxt returns the (cached) robots.txt that applies to the given URL
+func (c *Crawler) RobotsTxt(ctx context.Context, urlStr string) (*RobotsTxt, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	return c.robotsFor(ctx, u)
+}
+
+// robotsFor fetches robots.txt once per host and shares the result between
+// concurrent callers
+func (c *Crawler) robotsFor(ctx context.Context, u *url.URL) (*RobotsTxt, error) {
+	key := strings.ToLower(u.Scheme + "://" + u.Host)
+
+	c.robots.mu.Lock()
+	if c.robots.entries == nil {
+		c.robots.entries = make(map[string]*robotsEntry)
+	}
+	entry, ok := c.robots.entries[key]
+	if ok {
+		select {
+		case <-entry.ready:
+			if time.Since(entry.fetchedAt) > entry.ttl() {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		c.robots.entries[key] = entry
+		c.robots.mu.Unlock()
+
+		entry.robots, entry.err = c.fetchRobotsTxt(ctx, key+"/robots.txt")
+		entry.fetchedAt = time.Now()
+		if entry.err != nil {
+			// Don't cache failures caused by the caller giving up
+			c.robots.mu.Lock()
+			delete(c.robots.entries, key)
+			c.robots.mu.Unlock()
+		}
+		close(entry.ready)
+		return entry.robots, entry.err
+	}
+	c.robots.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.robots, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchRobotsTxt downloads and parses robots.txt. Following Google's
+// handling, a 4xx response allows everything while a 5xx response or a
+// network error disallows everything.
+func (c *Crawler) fetchRobotsTxt(ctx context.Context, robotsURL string) (*RobotsTxt, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "text/plain,*/*;q=0.8")
+
+	// robots.txt has no Crawl-delay for itself yet
+	resp, _, err := c.sendRequest(ctx, c.client, req, 0)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &RobotsTxt{disallowAll: true}, nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &RobotsTxt{disallowAll: true}, nil
+	case resp.StatusCode >= 400:
+		return &RobotsTxt{}, nil
+	case resp.StatusCode >= 300:
+		// Unresolved redirect (e.g. too many hops)
+		return &RobotsTxt{}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsTxtSize))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &RobotsTxt{disallowAll: true}, nil
+	}
+
+	return ParseRobotsTxt(body), nil
+}
@@ -0,0 +1,247 @@
+package crawler
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testUserAgent = "PhoenixSEO/1.0 (+https://phoenix-seo.com/bot)"
+
+func TestRobotsTxtIsAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		robots    string
+		userAgent string
+		url       string
+		want      bool
+	}{
+		{
+			name:   "no rules",
+			robots: "",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "disallow prefix",
+			robots: "User-agent: *\nDisallow: /private",
+			url:    "https://example.com/private/page",
+			want:   false,
+		},
+		{
+			name:   "empty disallow allows everything",
+			robots: "User-agent: *\nDisallow:",
+			url:    "https://example.com/private",
+			want:   true,
+		},
+		{
+			name:   "longest match wins",
+			robots: "User-agent: *\nDisallow: /shop\nAllow: /shop/public",
+			url:    "https://example.com/shop/public/item",
+			want:   true,
+		},
+		{
+			name:   "allow wins a tie",
+			robots: "User-agent: *\nDisallow: /page\nAllow: /page",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "wildcard",
+			robots: "User-agent: *\nDisallow: /*.pdf",
+			url:    "https://example.com/files/report.pdf",
+			want:   false,
+		},
+		{
+			name:   "end anchor matches the end",
+			robots: "User-agent: *\nDisallow: /*.php$",
+			url:    "https://example.com/index.php",
+			want:   false,
+		},
+		{
+			name:   "end anchor does not match a longer path",
+			robots: "User-agent: *\nDisallow: /*.php$",
+			url:    "https://example.com/index.php?page=2",
+			want:   true,
+		},
+		{
+			name:   "query string is matched",
+			robots: "User-agent: *\nDisallow: /*?sort=",
+			url:    "https://example.com/list?sort=price",
+			want:   false,
+		},
+		{
+			name:   "percent escapes compare case-insensitively",
+			robots: "User-agent: *\nDisallow: /caf%c3%a9",
+			url:    "https://example.com/caf%C3%A9/menu",
+			want:   false,
+		},
+		{
+			name:   "non-ASCII pattern matches the escaped path",
+			robots: "User-agent: *\nDisallow: /café",
+			url:    "https://example.com/caf%C3%A9",
+			want:   false,
+		},
+		{
+			name:   "robots.txt is always allowed",
+			robots: "User-agent: *\nDisallow: /",
+			url:    "https://example.com/robots.txt",
+			want:   true,
+		},
+		{
+			name:   "group for the crawler replaces the wildcard group",
+			robots: "User-agent: *\nDisallow: /\n\nUser-agent: phoenixseo\nDisallow: /admin",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "user agent is matched case-insensitively",
+			robots: "User-agent: PHOENIXSEO\nDisallow: /",
+			url:    "https://example.com/page",
+			want:   false,
+		},
+		{
+			name:   "group for a prefix of the product token does not apply",
+			robots: "User-agent: phoenix\nDisallow: /",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "group for another crawler does not apply",
+			robots: "User-agent: googlebot\nDisallow: /",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "consecutive user agents share a group",
+			robots: "User-agent: googlebot\nUser-agent: phoenixseo\nDisallow: /shared",
+			url:    "https://example.com/shared",
+			want:   false,
+		},
+		{
+			name:   "groups for the crawler are merged",
+			robots: "User-agent: phoenixseo\nDisallow: /a\n\nUser-agent: phoenixseo\nDisallow: /b",
+			url:    "https://example.com/b",
+			want:   false,
+		},
+		{
+			name:   "rules before any user agent are ignored",
+			robots: "Disallow: /\nUser-agent: *\nDisallow: /private",
+			url:    "https://example.com/page",
+			want:   true,
+		},
+		{
+			name:   "comments and BOM are ignored",
+			robots: "\ufeffUser-agent: * # everyone\nDisallow: /tmp # scratch",
+			url:    "https://example.com/tmp/file",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			userAgent := tt.userAgent
+			if userAgent == "" {
+				userAgent = testUserAgent
+			}
+			robots := ParseRobotsTxt([]byte(tt.robots))
+			if got := robots.IsAllowed(userAgent, u); got != tt.want {
+				t.Errorf("IsAllowed(%s) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsTxtDisallowAll(t *testing.T) {
+	robots := &RobotsTxt{disallowAll: true}
+	for _, rawURL := range []string{"https://example.com/", "https://example.com/page"} {
+		u, _ := url.Parse(rawURL)
+		if robots.IsAllowed(testUserAgent, u) {
+			t.Errorf("IsAllowed(%s) = true for an unreachable robots.txt", rawURL)
+		}
+	}
+	u, _ := url.Parse("https://example.com/robots.txt")
+	if !robots.IsAllowed(testUserAgent, u) {
+		t.Error("robots.txt itself should stay allowed")
+	}
+}
+
+func TestRobotsTxtCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		want   time.Duration
+	}{
+		{"none", "User-agent: *\nDisallow: /private", 0},
+		{"seconds", "User-agent: *\nCrawl-delay: 2", 2 * time.Second},
+		{"fraction", "User-agent: *\nCrawl-delay: 0.5", 500 * time.Millisecond},
+		{"invalid", "User-agent: *\nCrawl-delay: soon", 0},
+		{"negative", "User-agent: *\nCrawl-delay: -1", 0},
+		{"crawler group wins", "User-agent: *\nCrawl-delay: 10\n\nUser-agent: phoenixseo\nCrawl-delay: 1", time.Second},
+		{"other crawler", "User-agent: bingbot\nCrawl-delay: 10", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robots := ParseRobotsTxt([]byte(tt.robots))
+			if got := robots.CrawlDelay(testUserAgent); got != tt.want {
+				t.Errorf("CrawlDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRobotsTxtSitemaps(t *testing.T) {
+	robots := ParseRobotsTxt([]byte("Sitemap: https://example.com/a.xml\nUser-agent: *\nDisallow:\nsitemap: https://example.com/b.xml\nSitemap:"))
+	want := []string{"https://example.com/a.xml", "https://example.com/b.xml"}
+	if len(robots.Sitemaps) != len(want) {
+		t.Fatalf("Sitemaps = %v, want %v", robots.Sitemaps, want)
+	}
+	for i := range want {
+		if robots.Sitemaps[i] != want[i] {
+			t.Errorf("Sitemaps[%d] = %q, want %q", i, robots.Sitemaps[i], want[i])
+		}
+	}
+}
+
+func TestRobotsProductToken(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"PhoenixSEO/1.0 (+https://phoenix-seo.com/bot)", "phoenixseo"},
+		{"Googlebot", "googlebot"},
+		{"  Bingbot ; compatible", "bingbot"},
+		{"Mozilla/5.0 (compatible; PhoenixSEO/1.0)", "mozilla"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := robotsProductToken(tt.userAgent); got != tt.want {
+			t.Errorf("robotsProductToken(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsEntryTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots *RobotsTxt
+		want   time.Duration
+	}{
+		{"fetched", &RobotsTxt{}, robotsTxtTTL},
+		{"unreachable", &RobotsTxt{disallowAll: true}, robotsTxtErrorTTL},
+		{"failed", nil, robotsTxtTTL},
+	}
+
+	for _, tt := range tests {
+		entry := &robotsEntry{robots: tt.robots}
+		if got := entry.ttl(); got != tt.want {
+			t.Errorf("%s: ttl() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Subdomain policies of a crawl scope
+const (
+	// SubdomainsWWW treats the start host and its www/apex counterpart as internal (default)
+	SubdomainsWWW = "www"
+	// SubdomainsNone only treats the exact start host as internal
+	SubdomainsNone = "none"
+	// SubdomainsAll treats every subdomain of the start host's registrable
+	// domain as internal, e.g. shop.example.com for a crawl of blog.example.com
+	SubdomainsAll = "all"
+)
+
+// Reasons for skipping a discovered URL
+const (
+	SkipExternal      = "external"
+	SkipExcludedPath  = "excluded_path"
+	SkipNotIncluded   = "not_included"
+	SkipTooManyParams = "too_many_params"
+	// SkipBlockedByRobots is a URL that robots.txt disallows. It is counted
+	// when the crawler reaches it, since robots.txt is fetched lazily.
+	SkipBlockedByRobots = "blocked_by_robots_txt"
+)
+
+// CrawlScope decides which discovered URLs belong to a site crawl. Path
+// patterns use robots.txt syntax: they match by prefix and support "*" and a
+// trailing "$".
+type CrawlScope struct {
+	IncludePaths []string
+	ExcludePaths []string
+	// Subdomains is one of SubdomainsWWW (default), SubdomainsNone or SubdomainsAll
+	Subdomains string
+	// MaxURLParams skips URLs with more query parameters (0 = unlimited)
+	MaxURLParams int
+}
+
+// scopeChecker applies a CrawlScope relative to the start host of a crawl
+type scopeChecker struct {
+	scope CrawlScope
+	host  string
+	twin  string
+	// root is the registrable domain of the start host, with its port
+	root string
+}
+
+// newScopeChecker creates a checker for a crawl starting at startURL;
+// startURL must already be normalized
+func newScopeChecker(scope CrawlScope, startURL *url.URL) *scopeChecker {
+	host := strings.ToLower(startURL.Host)
+	apex := strings.TrimPrefix(host, "www.")
+	twin := "www." + host
+	if apex != host {
+		twin = apex
+	}
+
+	// IP addresses and hosts like localhost have no registrable domain
+	root := apex
+	hostname := strings.ToLower(startURL.Hostname())
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(hostname); err == nil && net.ParseIP(hostname) == nil {
+		root = domain
+		if port := startURL.Port(); port != "" {
+			root = net.JoinHostPort(domain, port)
+		}
+	}
+	return &scopeChecker{
+		scope: scope,
+		host:  host,
+		twin:  twin,
+		root:  root,
+	}
+}
+
+// check returns "" if the normalized URL is in scope, otherwise the skip reason
+func (s *scopeChecker) check(u *url.URL) string {
+	if !s.isInternalHost(u.Host) {
+		return SkipExternal
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	for _, pattern := range s.scope.ExcludePaths {
+		if matchRobotsPattern(pattern, path) {
+			return SkipExcludedPath
+		}
+	}
+	if len(s.scope.IncludePaths) > 0 {
+		included := false
+		for _, pattern := range s.scope.IncludePaths {
+			if matchRobotsPattern(pattern, path) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return SkipNotIncluded
+		}
+	}
+
+	if s.scope.MaxURLParams > 0 && u.RawQuery != "" {
+		if len(strings.Split(u.RawQuery, "&")) > s.scope.MaxURLParams {
+			return SkipTooManyParams
+		}
+	}
+
+	return ""
+}
+
+// isInternalHost applies the subdomain policy to a lowercase host
+func (s *scopeChecker) isInternalHost(host string) bool {
+	if host == s.host {
+		return true
+	}
+	switch s.scope.Subdomains {
+	case SubdomainsNone:
+		return false
+	case SubdomainsAll:
+		return host == s.root || strings.HasSuffix(host, "."+s.root)
+	default:
+		return host == s.twin
+	}
+}
@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// TLSCertificate is the leaf certificate and protocol of the connection
+// that served the page
+type TLSCertificate struct {
+	Version   string
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// DaysRemaining returns the number of days until the certificate expires
+func (t *TLSCertificate) DaysRemaining(now time.Time) int {
+	return int(t.NotAfter.Sub(now).Hours() / 24)
+}
+
+// Cookie is a cookie set by the page response. The value is not kept.
+type Cookie struct {
+	Name     string
+	Domain   string
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	SameSite string // Strict, Lax, None or empty if not set
+}
+
+// MixedContent is a resource loaded over HTTP by an HTTPS page
+type MixedContent struct {
+	URL     string
+	Element string
+	// Active content (scripts, stylesheets, frames, objects) is blocked by browsers
+	Active bool
+}
+
+// parseTLS records the certificate of the connection
+func (r *CrawlResult) parseTLS(state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	cert := state.PeerCertificates[0]
+	issuer := cert.Issuer.CommonName
+	if len(cert.Issuer.Organization) > 0 {
+		issuer = cert.Issuer.Organization[0]
+		if cert.Issuer.CommonName != "" {
+			issuer += " (" + cert.Issuer.CommonName + ")"
+		}
+	}
+	r.TLS = &TLSCertificate{
+		Version:   tls.VersionName(state.Version),
+		Subject:   cert.Subject.CommonName,
+		Issuer:    issuer,
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// parseCookies records the Set-Cookie headers of the response without values.
+// The security audit only needs the attributes, and values may be session
+// tokens. The raw Set-Cookie headers in Headers are redacted separately by
+// responseHeaders for authenticated crawls.
+func (r *CrawlResult) parseCookies(resp *http.Response) {
+	for _, cookie := range resp.Cookies() {
+		sameSite := ""
+		switch cookie.SameSite {
+		case http.SameSiteStrictMode:
+			sameSite = "Strict"
+		case http.SameSiteLaxMode:
+			sameSite = "Lax"
+		case http.SameSiteNoneMode:
+			sameSite = "None"
+		}
+		r.Cookies = append(r.Cookies, Cookie{
+			Name:     cookie.Name,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: sameSite,
+		})
+	}
+}
+
+// findMixedContent collects the resources an HTTPS page loads over HTTP
+func (c *Crawler) findMixedContent(n *html.Node, result *CrawlResult, baseURL *url.URL) {
+	if n.Type == html.ElementNode {
+		add := func(ref string, active bool) {
+			ref = strings.TrimSpace(ref)
+			if ref == "" {
+				return
+			}
+			abs := c.makeAbsolute(ref, baseURL)
+			if strings.HasPrefix(strings.ToLower(abs), "http://") {
+				result.MixedContent = append(result.MixedContent, MixedContent{URL: abs, Element: n.Data, Active: active})
+			}
+		}
+
+		switch n.Data {
+		case "script", "iframe", "frame", "embed":
+			add(c.getAttr(n, "src"), true)
+		case "object":
+			add(c.getAttr(n, "data"), true)
+		case "link":
+			rel := c.getAttr(n, "rel")
+			switch {
+			case hasRel(rel, "stylesheet"):
+				add(c.getAttr(n, "href"), true)
+			case hasRel(rel, "icon"), hasRel(rel, "preload"):
+				add(c.getAttr(n, "href"), false)
+			}
+		case "img", "source", "audio", "video", "track":
+			add(c.getAttr(n, "src"), false)
+			add(c.getAttr(n, "poster"), false)
+			for _, candidate := range strings.Split(c.getAttr(n, "srcset"), ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					add(fields[0], false)
+				}
+			}
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.findMixedContent(child, result, baseURL)
+	}
+}
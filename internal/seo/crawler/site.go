@@ -0,0 +1,491 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultMaxPages is used when a site crawl sets no page limit
+	defaultMaxPages = 500
+	// defaultMaxPerHost is the default number of parallel requests per host
+	defaultMaxPerHost = 2
+)
+
+// SiteCrawlOptions configures a multi-page site crawl. Zero values fall
+// back to the crawler's defaults.
+type SiteCrawlOptions struct {
+	// MaxPages is the number of pages to crawl. URLs that fail to fetch or
+	// are blocked by robots.txt do not count, but the crawl also stops after
+	// MaxPages failures.
+	MaxPages    int
+	MaxDepth    int
+	Concurrency int
+	MaxPerHost  int
+	// UseSitemaps seeds the crawl with the URLs listed in the site's XML sitemaps
+	UseSitemaps bool
+	// Normalize controls how discovered URLs are canonicalized and deduplicated
+	Normalize URLNormalizer
+	// Scope decides which discovered URLs are crawled
+	Scope CrawlScope
+	// CheckLinks verifies all links and images of the crawled pages
+	CheckLinks bool
+	LinkCheck  LinkCheckOptions
+	// CrawlID identifies a resumable crawl when the crawler has a checkpoint
+	// directory. Crawling again with the same ID continues where the last run
+	// stopped; a higher MaxPages extends a finished crawl. The other options
+	// that decide which URLs are crawled must stay the same.
+	CrawlID string
+	// PreviousCrawlID re-crawls against a finished checkpointed crawl: pages
+	// are requested conditionally and reused if unchanged, and the result
+	// lists what changed since
+	PreviousCrawlID string
+	// DuplicateThreshold is the similarity from which pages count as
+	// near-duplicates, between 0 and 1 (default 0.9)
+	DuplicateThreshold float64
+}
+
+// SiteCrawlResult represents the result of crawling multiple pages of a site
+type SiteCrawlResult struct {
+	// CrawlID is set when the crawl is checkpointed and can be resumed
+	CrawlID       string
+	StartURL      string
+	Pages         []*CrawlResult
+	Failed        []FailedURL
+	SitemapURLs   []SitemapURL
+	SitemapIssues []SitemapIssue
+	LinkCheck     *LinkCheckReport
+	// HreflangIssues are problems of the hreflang annotations across the site
+	HreflangIssues []HreflangIssue
+	// Duplicates lists duplicate content, titles, descriptions and H1s
+	Duplicates *DuplicateReport
+	// LinkGraph is the internal link structure of the crawled pages
+	LinkGraph *LinkGraph
+	// Changes compares the pages with the previous crawl, if one was given
+	Changes *CrawlChanges
+	// Skipped counts discovered URLs that were out of scope, by skip reason
+	Skipped map[string]int
+	Errors  []string
+}
+
+// FailedURL is a URL that could not be crawled
+type FailedURL struct {
+	URL      string
+	Depth    int
+	FoundVia string
+	Error    string
+}
+
+// siteOutcome is the result of crawling one frontier item
+type siteOutcome struct {
+	item   frontierItem
+	seq    int
+	result *CrawlResult
+	err    error
+}
+
+// blocked reports whether robots.txt disallowed the URL of the outcome
+func (o siteOutcome) blocked() bool {
+	return o.err == nil && isBlockedURL(o.result)
+}
+
+// isBlockedURL reports whether robots.txt disallowed the URL of a result
+// itself, so no request was made. A redirect into a disallowed URL is a
+// crawled page.
+func isBlockedURL(result *CrawlResult) bool {
+	return result.BlockedByRobotsTxt && len(result.RedirectChain) == 0
+}
+
+// CrawlSite crawls a site with a bounded pool of workers. Pages are
+// discovered breadth-first from the start URL (and optionally the sitemaps)
+// up to the configured depth and page limit. If the context is cancelled,
+// the pages crawled so far are returned together with the context error.
+// With a checkpoint directory the crawl is saved while it runs and can be
+// resumed with the same CrawlID.
+func (c *Crawler) CrawlSite(ctx context.Context, startURL string, opts SiteCrawlOptions) (*SiteCrawlResult, error) {
+	normalizer := &opts.Normalize
+	start, err := normalizer.Normalize(startURL)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := url.Parse(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	scope := newScopeChecker(opts.Scope, baseURL)
+
+	maxPages := opts.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = c.maxDepth
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = c.maxConcurrent
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	perHost := opts.MaxPerHost
+	if perHost <= 0 {
+		perHost = defaultMaxPerHost
+	}
+
+	site := &SiteCrawlResult{
+		StartURL: startURL,
+		Pages:    make([]*CrawlResult, 0),
+		Failed:   []FailedURL{},
+		Skipped:  make(map[string]int),
+		Errors:   []string{},
+	}
+
+	queue := newFrontier()
+
+	// enqueue normalizes a discovered URL and queues it if it is new and in scope
+	enqueue := func(rawURL string, depth int, foundVia string) {
+		normalized, err := normalizer.Normalize(rawURL)
+		if err != nil || !queue.markSeen(normalized) {
+			return
+		}
+		u, err := url.Parse(normalized)
+		if err != nil {
+			return
+		}
+		if reason := scope.check(u); reason != "" {
+			site.Skipped[reason]++
+			return
+		}
+		queue.enqueue(frontierItem{url: normalized, depth: depth, foundVia: foundVia})
+	}
+
+	var previous map[string]*CrawlResult
+	if opts.PreviousCrawlID != "" {
+		previous, err = c.loadPreviousCrawl(opts.PreviousCrawlID, normalizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	definition := crawlDefinition{
+		MaxDepth:    maxDepth,
+		UseSitemaps: opts.UseSitemaps,
+		Scope:       opts.Scope,
+		Normalize:   opts.Normalize,
+	}
+
+	// Pages of a resumed crawl are not fetched again
+	var done []siteOutcome
+	var cp *checkpoint
+	seq := 0
+	if c.checkpointDir != "" {
+		if opts.CrawlID == "" {
+			opts.CrawlID = uuid.NewString()
+		}
+		var state *crawlState
+		var records []checkpointRecord
+		cp, state, records, err = openCheckpoint(c.checkpointDir, opts.CrawlID)
+		if err != nil {
+			return nil, err
+		}
+		defer cp.close()
+		site.CrawlID = opts.CrawlID
+
+		if state != nil {
+			if state.StartURL != start {
+				return nil, fmt.Errorf("%w: crawl %s started at %s, not %s", ErrCrawlMismatch, opts.CrawlID, state.StartURL, start)
+			}
+			if option := definition.mismatch(state.Definition); option != "" {
+				return nil, fmt.Errorf("%w: the %s of crawl %s differs", ErrCrawlMismatch, option, opts.CrawlID)
+			}
+			crawled := make(map[string]bool, len(records))
+			for _, record := range records {
+				out := siteOutcome{
+					item:   frontierItem{url: record.Item.URL, depth: record.Item.Depth, foundVia: record.Item.FoundVia},
+					seq:    record.Seq,
+					result: record.Result,
+				}
+				if record.Error != "" || record.Result == nil {
+					out.err = errors.New(record.Error)
+				}
+				done = append(done, out)
+				crawled[record.Item.URL] = true
+				seq = max(seq, record.Seq+1)
+			}
+			queue.restore(state, crawled)
+			site.Skipped = state.Skipped
+			site.SitemapURLs = state.SitemapURLs
+			site.Errors = append(site.Errors, state.Errors...)
+			if site.Skipped == nil {
+				site.Skipped = make(map[string]int)
+			}
+
+			// Pages finished after the last save may have unqueued links
+			for _, out := range done {
+				if out.err == nil && !out.blocked() && out.item.depth < maxDepth {
+					for _, link := range out.result.Links {
+						enqueue(link, out.item.depth+1, FoundViaLink)
+					}
+				}
+			}
+		}
+	}
+
+	if len(done) == 0 && queue.len() == 0 {
+		queue.push(start, 0, FoundViaStart)
+
+		// Seed the frontier from the sitemaps
+		if opts.UseSitemaps {
+			sitemapURLs, errs, err := c.CollectSitemapURLs(ctx, startURL)
+			if err != nil {
+				return nil, err
+			}
+			site.SitemapURLs = sitemapURLs
+			site.Errors = append(site.Errors, errs...)
+			for _, entry := range sitemapURLs {
+				enqueue(entry.Loc, 0, FoundViaSitemap)
+			}
+		}
+	}
+
+	// inFlightItems are saved with the frontier, so pages that were being
+	// crawled during a crash are crawled again on resume
+	inFlightItems := make(map[int]frontierItem)
+	saveCheckpoint := func(finished bool) {
+		if cp == nil {
+			return
+		}
+		state := &crawlState{
+			StartURL:    start,
+			Skipped:     site.Skipped,
+			SitemapURLs: site.SitemapURLs,
+			Errors:      site.Errors,
+			Finished:    finished,
+			Definition:  definition,
+		}
+		state.Queue, state.Seen = queue.snapshot(inFlightItems)
+		if err := cp.save(state); err != nil {
+			site.Errors = append(site.Errors, fmt.Sprintf("checkpoint: %v", err))
+		}
+	}
+	saveCheckpoint(false)
+
+	jobs := make(chan siteOutcome)
+	outcomes := make(chan siteOutcome)
+	hosts := newHostLimiter(perHost)
+	cache := &crawlCache{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.result, job.err = c.crawlWithHostLimit(ctx, hosts, cache, job.item.url, previous[job.item.url])
+				outcomes <- job
+			}
+		}()
+	}
+
+	// The coordinator owns the frontier: it hands out URLs to idle workers
+	// and queues the links of every finished page. Pages in flight count
+	// against the page limit until they turn out to have failed or to be
+	// blocked by robots.txt.
+	inFlight, crawled, failed := 0, 0, 0
+	for _, out := range done {
+		switch {
+		case out.err != nil:
+			failed++
+		case !out.blocked():
+			crawled++
+		}
+	}
+	for {
+		var next siteOutcome
+		var send chan siteOutcome
+		if item, ok := queue.peek(); ok && crawled+inFlight < maxPages && failed < maxPages && ctx.Err() == nil {
+			next = siteOutcome{item: item, seq: seq}
+			send = jobs
+		}
+		if send == nil && inFlight == 0 {
+			break
+		}
+
+		select {
+		case send <- next:
+			queue.pop()
+			inFlightItems[next.seq] = next.item
+			inFlight++
+			seq++
+		case out := <-outcomes:
+			inFlight--
+			done = append(done, out)
+			switch {
+			case out.err != nil:
+				failed++
+			case out.blocked():
+				// Reported under Skipped, no links to follow
+			default:
+				crawled++
+				out.result.Depth = out.item.depth
+				out.result.FoundVia = out.item.foundVia
+				if previous != nil && out.result.Change == nil {
+					out.result.Change = &PageChange{Status: ChangeNew}
+				}
+			}
+			// Pages interrupted by cancellation stay in the saved frontier
+			// and leave the crawl unfinished
+			if out.err == nil || ctx.Err() == nil {
+				delete(inFlightItems, out.seq)
+			}
+			if cp != nil && (out.err == nil || ctx.Err() == nil) {
+				record := checkpointRecord{
+					Seq:    out.seq,
+					Item:   checkpointItem{URL: out.item.url, Depth: out.item.depth, FoundVia: out.item.foundVia},
+					Result: out.result,
+				}
+				if out.err != nil {
+					record.Error = out.err.Error()
+				}
+				if err := cp.append(record); err != nil {
+					site.Errors = append(site.Errors, fmt.Sprintf("checkpoint: %v", err))
+				}
+			}
+			if out.err != nil || out.blocked() || out.item.depth >= maxDepth {
+				if cp != nil && cp.due() {
+					saveCheckpoint(false)
+				}
+				continue
+			}
+			for _, link := range out.result.Links {
+				enqueue(link, out.item.depth+1, FoundViaLink)
+			}
+			if cp != nil && cp.due() {
+				saveCheckpoint(false)
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	finished := queue.len() == 0 && len(inFlightItems) == 0
+	saveCheckpoint(finished)
+
+	// Report pages in discovery order regardless of which worker finished first.
+	// Blocked URLs are skipped, but kept for the sitemap audit and the link check.
+	sort.Slice(done, func(i, j int) bool { return done[i].seq < done[j].seq })
+	var blocked []*CrawlResult
+	for _, out := range done {
+		switch {
+		case out.err != nil:
+			site.Failed = append(site.Failed, FailedURL{
+				URL:      out.item.url,
+				Depth:    out.item.depth,
+				FoundVia: out.item.foundVia,
+				Error:    out.err.Error(),
+			})
+		case out.blocked():
+			site.Skipped[SkipBlockedByRobots]++
+			blocked = append(blocked, out.result)
+		default:
+			site.Pages = append(site.Pages, out.result)
+		}
+	}
+
+	if len(site.SitemapURLs) > 0 {
+		pages := make(map[string]*CrawlResult, len(site.Pages)+len(blocked))
+		for _, page := range slices.Concat(site.Pages, blocked) {
+			pages[page.URL] = page
+		}
+		site.SitemapIssues = auditSitemapURLs(site.SitemapURLs, pages, normalizer)
+	}
+
+	site.HreflangIssues = auditHreflang(site.Pages, site.SitemapURLs, normalizer)
+	site.Duplicates = auditDuplicates(site.Pages, opts.DuplicateThreshold)
+	if previous != nil {
+		site.Changes = summarizeChanges(opts.PreviousCrawlID, site.Pages, blocked, previous, normalizer, finished)
+	}
+	site.LinkGraph = buildLinkGraph(site.Pages, site.SitemapURLs, start, normalizer, finished)
+
+	if opts.CheckLinks && ctx.Err() == nil {
+		// Crawled pages are not requested a second time
+		crawled := make(map[string]*CrawlResult, len(site.Pages)+len(blocked))
+		for _, page := range slices.Concat(site.Pages, blocked) {
+			crawled[page.URL] = page
+		}
+		known := func(urlStr string) (LinkStatus, bool) {
+			normalized, err := normalizer.Normalize(urlStr)
+			if err != nil {
+				return LinkStatus{}, false
+			}
+			if page, ok := crawled[normalized]; ok {
+				return pageLinkStatus(page)
+			}
+			return LinkStatus{}, false
+		}
+		site.LinkCheck, err = c.checkLinks(ctx, &cache.linkChecks, site.Pages, opts.LinkCheck, known)
+		if err != nil {
+			site.Errors = append(site.Errors, fmt.Sprintf("link check: %v", err))
+		}
+	}
+
+	return site, ctx.Err()
+}
+
+// crawlWithHostLimit crawls a page once a per-host slot is free. previous
+// is the page's result of an earlier crawl or nil.
+func (c *Crawler) crawlWithHostLimit(ctx context.Context, hosts *hostLimiter, cache *crawlCache, urlStr string, previous *CrawlResult) (*CrawlResult, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	release, err := hosts.acquire(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return c.crawlPage(ctx, cache, urlStr, previous)
+}
+
+// hostLimiter bounds the number of parallel requests per host
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+// newHostLimiter creates a limiter allowing limit parallel requests per host
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// acquire blocks until a slot for the host is free or ctx is done
+func (h *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h.mu.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.limit)
+		h.slots[host] = slots
+	}
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSite serves the given paths. Paths ending in .txt or .xml are served
+// as plain text and XML, everything else as HTML; unknown paths are 404.
+// "{{base}}" in a body is replaced with the server's URL.
+func newTestSite(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".txt"):
+			w.Header().Set("Content-Type", "text/plain")
+		case strings.HasSuffix(r.URL.Path, ".xml"):
+			w.Header().Set("Content-Type", "application/xml")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write([]byte(strings.ReplaceAll(body, "{{base}}", "http://"+r.Host)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestCrawler returns a crawler without delays or extra requests for
+// assets and not-found probes
+func newTestCrawler() *Crawler {
+	c := NewCrawler("PhoenixSEO/1.0", 5*time.Second, 5)
+	c.SetCrawlDelay(0)
+	c.SetCheckAssets(false)
+	c.SetSoft404Probe(false)
+	return c
+}
+
+// testPage returns an HTML page with a title and links to the given paths
+func testPage(title string, links ...string) string {
+	var b strings.Builder
+	b.WriteString("<html><head><title>" + title + "</title></head><body><h1>" + title + "</h1>")
+	for _, link := range links {
+		b.WriteString(`<a href="` + link + `">` + link + `</a>`)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func TestCrawlSite(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/robots.txt": "User-agent: *\nDisallow: /admin",
+		"/":           testPage("Home", "/a", "/admin/x", "/b", "/missing"),
+		"/a":          testPage("A", "/", "/a/deep"),
+		"/a/deep":     testPage("Deep", "/a/deeper"),
+		"/a/deeper":   testPage("Deeper"),
+		"/b":          testPage("B", "https://external.example/"),
+		"/admin/x":    testPage("Admin"),
+	})
+
+	tests := []struct {
+		name        string
+		opts        SiteCrawlOptions
+		wantPages   []string
+		wantFailed  int
+		wantSkipped map[string]int
+	}{
+		{
+			name:        "whole site",
+			opts:        SiteCrawlOptions{Concurrency: 1},
+			wantPages:   []string{"/", "/a", "/b", "/missing", "/a/deep", "/a/deeper"},
+			wantSkipped: map[string]int{SkipBlockedByRobots: 1, SkipExternal: 1},
+		},
+		{
+			name:        "depth limit",
+			opts:        SiteCrawlOptions{Concurrency: 1, MaxDepth: 1},
+			wantPages:   []string{"/", "/a", "/b", "/missing"},
+			wantSkipped: map[string]int{SkipBlockedByRobots: 1, SkipExternal: 0},
+		},
+		{
+			name:        "blocked URLs do not use up the page limit",
+			opts:        SiteCrawlOptions{Concurrency: 1, MaxPages: 3},
+			wantPages:   []string{"/", "/a", "/b"},
+			wantSkipped: map[string]int{SkipBlockedByRobots: 1},
+		},
+		{
+			name:        "excluded paths",
+			opts:        SiteCrawlOptions{Concurrency: 2, Scope: CrawlScope{ExcludePaths: []string{"/a$"}}},
+			wantPages:   []string{"/", "/b", "/missing"},
+			wantSkipped: map[string]int{SkipBlockedByRobots: 1, SkipExcludedPath: 1, SkipExternal: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, err := newTestCrawler().CrawlSite(context.Background(), srv.URL, tt.opts)
+			if err != nil {
+				t.Fatalf("CrawlSite() error = %v", err)
+			}
+
+			got := pageURLs(site.Pages)
+			if len(got) != len(tt.wantPages) {
+				t.Fatalf("Pages = %v, want %v", got, tt.wantPages)
+			}
+			for i, path := range tt.wantPages {
+				if got[i] != srv.URL+path {
+					t.Errorf("Pages[%d] = %s, want %s", i, got[i], srv.URL+path)
+				}
+			}
+			for _, page := range site.Pages {
+				if page.BlockedByRobotsTxt || page.StatusCode == 0 {
+					t.Errorf("page %s was not crawled: %+v", page.URL, page)
+				}
+			}
+			if len(site.Failed) != tt.wantFailed {
+				t.Errorf("Failed = %+v, want %d", site.Failed, tt.wantFailed)
+			}
+			for reason, want := range tt.wantSkipped {
+				if site.Skipped[reason] != want {
+					t.Errorf("Skipped[%s] = %d, want %d", reason, site.Skipped[reason], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawlSiteBlockedSitemapURL(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/robots.txt":  "User-agent: *\nDisallow: /private",
+		"/":            testPage("Home"),
+		"/private":     testPage("Private"),
+		"/sitemap.xml": `<urlset><url><loc>{{base}}/</loc></url><url><loc>{{base}}/private</loc></url></urlset>`,
+	})
+
+	site, err := newTestCrawler().CrawlSite(context.Background(), srv.URL, SiteCrawlOptions{UseSitemaps: true})
+	if err != nil {
+		t.Fatalf("CrawlSite() error = %v", err)
+	}
+	if got := pageURLs(site.Pages); len(got) != 1 {
+		t.Errorf("Pages = %v, want only the home page", got)
+	}
+	if site.Skipped[SkipBlockedByRobots] != 1 {
+		t.Errorf("Skipped = %v, want one URL blocked by robots.txt", site.Skipped)
+	}
+	if len(site.SitemapIssues) != 1 || site.SitemapIssues[0].Issue != SitemapIssueBlockedByRobots {
+		t.Errorf("SitemapIssues = %+v, want the blocked URL", site.SitemapIssues)
+	}
+}
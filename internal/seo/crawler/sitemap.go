@@ -0,0 +1,388 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemapSize is the maximum uncompressed size of a sitemap file (per sitemaps.org)
+	maxSitemapSize = 50 * 1024 * 1024
+	// maxSitemapURLs caps the number of URLs collected across all sitemaps of a site
+	maxSitemapURLs = 50000
+	// maxSitemapIndexDepth limits how deep nested sitemap index files are followed
+	maxSitemapIndexDepth = 3
+)
+
+// Sitemap represents a parsed XML sitemap or sitemap index file
+type Sitemap struct {
+	URL      string
+	IsIndex  bool
+	URLs     []SitemapURL
+	Sitemaps []SitemapRef
+}
+
+// SitemapRef is an entry of a sitemap index file
+type SitemapRef struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// SitemapURL is a <url> entry of a sitemap
+type SitemapURL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   float64
+	Images     []SitemapImage
+	Alternates []SitemapAlternate
+	Sitemap    string
+}
+
+// SitemapImage is an image:image entry of a sitemap URL
+type SitemapImage struct {
+	Loc     string
+	Caption string
+	Title   string
+}
+
+// SitemapAlternate is an xhtml:link hreflang alternate of a sitemap URL
+type SitemapAlternate struct {
+	Hreflang string
+	Href     string
+}
+
+// SitemapIssue describes a sitemap URL that should not be listed in a sitemap
+type SitemapIssue struct {
+	URL        string
+	Sitemap    string
+	Issue      string // non_200, redirect, noindex, canonicalized, blocked_by_robots
+	StatusCode int
+	Target     string
+}
+
+// Sitemap issue types
+const (
+	SitemapIssueNon200          = "non_200"
+	SitemapIssueRedirect        = "redirect"
+	SitemapIssueNoindex         = "noindex"
+	SitemapIssueCanonicalized   = "canonicalized"
+	SitemapIssueBlockedByRobots = "blocked_by_robots"
+)
+
+// xmlSitemap mirrors both <urlset> and <sitemapindex> documents; the image
+// and xhtml extensions are matched by their local element names
+type xmlSitemap struct {
+	XMLName  xml.Name
+	URLs     []xmlSitemapURL `xml:"url"`
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+type xmlSitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+	Images     []struct {
+		Loc     string `xml:"loc"`
+		Caption string `xml:"caption"`
+		Title   string `xml:"title"`
+	} `xml:"image"`
+	Links []struct {
+		Rel      string `xml:"rel,attr"`
+		Hreflang string `xml:"hreflang,attr"`
+		Href     string `xml:"href,attr"`
+	} `xml:"link"`
+}
+
+// ParseSitemap parses a sitemap or sitemap index. Gzip-compressed input is
+// detected and decompressed automatically.
+func ParseSitemap(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var doc xmlSitemap
+	decoder := xml.NewDecoder(io.LimitReader(r, maxSitemapSize))
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap XML: %w", err)
+	}
+
+	sitemap := &Sitemap{}
+	switch doc.XMLName.Local {
+	case "sitemapindex":
+		sitemap.IsIndex = true
+		for _, ref := range doc.Sitemaps {
+			loc := strings.TrimSpace(ref.Loc)
+			if loc == "" {
+				continue
+			}
+			sitemap.Sitemaps = append(sitemap.Sitemaps, SitemapRef{
+				Loc:     loc,
+				LastMod: parseW3CDate(ref.LastMod),
+			})
+		}
+	case "urlset":
+		for _, entry := range doc.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" {
+				continue
+			}
+			sitemapURL := SitemapURL{
+				Loc:        loc,
+				LastMod:    parseW3CDate(entry.LastMod),
+				ChangeFreq: strings.ToLower(strings.TrimSpace(entry.ChangeFreq)),
+				Priority:   0.5,
+			}
+			if priority, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err == nil {
+				sitemapURL.Priority = priority
+			}
+			for _, img := range entry.Images {
+				if loc := strings.TrimSpace(img.Loc); loc != "" {
+					sitemapURL.Images = append(sitemapURL.Images, SitemapImage{
+						Loc:     loc,
+						Caption: strings.TrimSpace(img.Caption),
+						Title:   strings.TrimSpace(img.Title),
+					})
+				}
+			}
+			for _, link := range entry.Links {
+				if strings.EqualFold(link.Rel, "alternate") && link.Hreflang != "" && link.Href != "" {
+					sitemapURL.Alternates = append(sitemapURL.Alternates, SitemapAlternate{
+						Hreflang: strings.TrimSpace(link.Hreflang),
+						Href:     strings.TrimSpace(link.Href),
+					})
+				}
+			}
+			sitemap.URLs = append(sitemap.URLs, sitemapURL)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected sitemap root element <%s>", doc.XMLName.Local)
+	}
+
+	return sitemap, nil
+}
+
+// parseW3CDate parses the W3C datetime formats allowed in <lastmod>
+func parseW3CDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// FetchSitemap downloads and parses a single sitemap or sitemap index
+func (c *Crawler) FetchSitemap(ctx context.Context, sitemapURL string) (*Sitemap, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/xml,text/xml;q=0.9,*/*;q=0.8")
+
+	resp, _, err := c.sendCheckRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s returned status %d", sitemapURL, resp.StatusCode)
+	}
+
+	sitemap, err := ParseSitemap(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sitemap.URL = sitemapURL
+	for i := range sitemap.URLs {
+		sitemap.URLs[i].Sitemap = sitemapURL
+	}
+
+	return sitemap, nil
+}
+
+// DiscoverSitemaps returns the sitemap URLs of a site: those listed in
+// robots.txt plus the conventional /sitemap.xml
+func (c *Crawler) DiscoverSitemaps(ctx context.Context, siteURL string) ([]string, error) {
+	listed, fallback, err := c.discoverSitemaps(ctx, siteURL)
+	if err != nil {
+		return nil, err
+	}
+	if fallback != "" {
+		listed = append(listed, fallback)
+	}
+	return listed, nil
+}
+
+// discoverSitemaps returns the sitemaps listed in robots.txt and, unless
+// robots.txt already lists it, the conventional /sitemap.xml location
+func (c *Crawler) discoverSitemaps(ctx context.Context, siteURL string) ([]string, string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	robots, err := c.robotsFor(ctx, u)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+
+	fallback := u.Scheme + "://" + u.Host + "/sitemap.xml"
+	seen := make(map[string]bool)
+	var listed []string
+	for _, loc := range robots.Sitemaps {
+		if !seen[loc] {
+			seen[loc] = true
+			listed = append(listed, loc)
+		}
+	}
+	if seen[fallback] {
+		fallback = ""
+	}
+
+	return listed, fallback, nil
+}
+
+// CollectSitemapURLs discovers all sitemaps of a site, expands sitemap
+// index files and returns the listed URLs. Sitemaps that could not be
+// fetched or parsed are reported as errors; a missing /sitemap.xml is only
+// reported if robots.txt lists no sitemaps either.
+func (c *Crawler) CollectSitemapURLs(ctx context.Context, siteURL string) ([]SitemapURL, []string, error) {
+	listed, fallback, err := c.discoverSitemaps(ctx, siteURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var urls []SitemapURL
+	var errs []string
+	visited := make(map[string]bool)
+	seenURLs := make(map[string]bool)
+
+	var collect func(loc string, depth int) error
+	collect = func(loc string, depth int) error {
+		if visited[loc] || len(urls) >= maxSitemapURLs || ctx.Err() != nil {
+			return nil
+		}
+		visited[loc] = true
+
+		sitemap, err := c.FetchSitemap(ctx, loc)
+		if err != nil {
+			return err
+		}
+
+		if sitemap.IsIndex {
+			if depth >= maxSitemapIndexDepth {
+				return fmt.Errorf("sitemap index %s nested too deeply", loc)
+			}
+			for _, ref := range sitemap.Sitemaps {
+				if err := collect(ref.Loc, depth+1); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+			return nil
+		}
+
+		for _, entry := range sitemap.URLs {
+			if len(urls) >= maxSitemapURLs {
+				return fmt.Errorf("sitemap URL limit of %d reached", maxSitemapURLs)
+			}
+			if !seenURLs[entry.Loc] {
+				seenURLs[entry.Loc] = true
+				urls = append(urls, entry)
+			}
+		}
+		return nil
+	}
+
+	for _, loc := range listed {
+		if err := collect(loc, 0); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if fallback != "" {
+		if err := collect(fallback, 0); err != nil && len(listed) == 0 {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return urls, errs, err
+	}
+	return urls, errs, nil
+}
+
+// auditSitemapURLs compares sitemap entries with their crawl results,
+// which are keyed by normalized URL, and reports URLs that do not belong in
+// a sitemap
+func auditSitemapURLs(entries []SitemapURL, pages map[string]*CrawlResult, normalizer *URLNormalizer) []SitemapIssue {
+	var issues []SitemapIssue
+	for _, entry := range entries {
+		loc, err := normalizer.Normalize(entry.Loc)
+		if err != nil {
+			continue
+		}
+		page, ok := pages[loc]
+		if !ok {
+			continue
+		}
+
+		issue := SitemapIssue{
+			URL:        entry.Loc,
+			Sitemap:    entry.Sitemap,
+			StatusCode: page.StatusCode,
+		}
+		switch {
+		case page.BlockedByRobotsTxt:
+			issue.Issue = SitemapIssueBlockedByRobots
+		case len(page.RedirectChain) > 0:
+			issue.Issue = SitemapIssueRedirect
+			issue.Target = page.FinalURL
+		case page.StatusCode != http.StatusOK:
+			issue.Issue = SitemapIssueNon200
+		case page.IsNoindex():
+			issue.Issue = SitemapIssueNoindex
+		case page.CanonicalURL != "" && !sameNormalizedURL(normalizer, page.CanonicalURL, page.URL):
+			issue.Issue = SitemapIssueCanonicalized
+			issue.Target = page.CanonicalURL
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// sameNormalizedURL reports whether two URLs normalize to the same form
+func sameNormalizedURL(normalizer *URLNormalizer, a, b string) bool {
+	na, errA := normalizer.Normalize(a)
+	nb, errB := normalizer.Normalize(b)
+	return errA == nil && errB == nil && na == nb
+}
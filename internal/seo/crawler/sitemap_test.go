@@ -0,0 +1,173 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"
+        xmlns:xhtml="http://www.w3.org/1999/xhtml">
+  <url>
+    <loc> https://example.com/ </loc>
+    <lastmod>2024-03-01</lastmod>
+    <changefreq>Daily</changefreq>
+    <priority>1.0</priority>
+    <image:image>
+      <image:loc>https://example.com/hero.jpg</image:loc>
+      <image:caption> Hero </image:caption>
+      <image:title>Title</image:title>
+    </image:image>
+    <image:image>
+      <image:caption>no location</image:caption>
+    </image:image>
+    <xhtml:link rel="alternate" hreflang="de" href="https://example.com/de/"/>
+    <xhtml:link rel="alternate" hreflang="en" href=""/>
+    <xhtml:link rel="canonical" hreflang="fr" href="https://example.com/fr/"/>
+  </url>
+  <url>
+    <loc>https://example.com/about</loc>
+    <priority>high</priority>
+  </url>
+  <url>
+    <loc></loc>
+  </url>
+</urlset>`
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://example.com/sitemap-pages.xml</loc>
+    <lastmod>2024-03-01T10:00:00+01:00</lastmod>
+  </sitemap>
+  <sitemap>
+    <loc> </loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/sitemap-posts.xml</loc>
+  </sitemap>
+</sitemapindex>`
+
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseSitemap(t *testing.T) {
+	wantURLSet := &Sitemap{
+		URLs: []SitemapURL{
+			{
+				Loc:        "https://example.com/",
+				LastMod:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				ChangeFreq: "daily",
+				Priority:   1.0,
+				Images:     []SitemapImage{{Loc: "https://example.com/hero.jpg", Caption: "Hero", Title: "Title"}},
+				Alternates: []SitemapAlternate{{Hreflang: "de", Href: "https://example.com/de/"}},
+			},
+			{
+				Loc:      "https://example.com/about",
+				Priority: 0.5,
+			},
+		},
+	}
+	wantIndex := &Sitemap{
+		IsIndex: true,
+		Sitemaps: []SitemapRef{
+			{Loc: "https://example.com/sitemap-pages.xml", LastMod: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+			{Loc: "https://example.com/sitemap-posts.xml"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    *Sitemap
+		wantErr bool
+	}{
+		{name: "urlset", input: testURLSet, want: wantURLSet},
+		{name: "gzip urlset", input: gzipString(t, testURLSet), want: wantURLSet},
+		{name: "sitemap index", input: testSitemapIndex, want: wantIndex},
+		{name: "gzip sitemap index", input: gzipString(t, testSitemapIndex), want: wantIndex},
+		{name: "empty urlset", input: `<urlset></urlset>`, want: &Sitemap{}},
+		{name: "unexpected root element", input: `<html><body>Not found</body></html>`, wantErr: true},
+		{name: "not XML", input: `User-agent: *`, wantErr: true},
+		{name: "empty", input: ``, wantErr: true},
+		{name: "corrupt gzip", input: "\x1f\x8bnot gzip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSitemap(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSitemap() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSitemap() error = %v", err)
+			}
+			if got.IsIndex != tt.want.IsIndex {
+				t.Errorf("IsIndex = %v, want %v", got.IsIndex, tt.want.IsIndex)
+			}
+			if len(got.URLs) != len(tt.want.URLs) {
+				t.Fatalf("URLs = %+v, want %+v", got.URLs, tt.want.URLs)
+			}
+			for i := range tt.want.URLs {
+				gotURL, wantURL := got.URLs[i], tt.want.URLs[i]
+				if !gotURL.LastMod.Equal(wantURL.LastMod) {
+					t.Errorf("URLs[%d].LastMod = %v, want %v", i, gotURL.LastMod, wantURL.LastMod)
+				}
+				gotURL.LastMod, wantURL.LastMod = time.Time{}, time.Time{}
+				if !reflect.DeepEqual(gotURL, wantURL) {
+					t.Errorf("URLs[%d] = %+v, want %+v", i, gotURL, wantURL)
+				}
+			}
+			if len(got.Sitemaps) != len(tt.want.Sitemaps) {
+				t.Fatalf("Sitemaps = %+v, want %+v", got.Sitemaps, tt.want.Sitemaps)
+			}
+			for i := range tt.want.Sitemaps {
+				gotRef, wantRef := got.Sitemaps[i], tt.want.Sitemaps[i]
+				if gotRef.Loc != wantRef.Loc || !gotRef.LastMod.Equal(wantRef.LastMod) {
+					t.Errorf("Sitemaps[%d] = %+v, want %+v", i, gotRef, wantRef)
+				}
+			}
+		})
+	}
+}
+
+func TestParseW3CDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-03-01T10:30:15+01:00", time.Date(2024, 3, 1, 9, 30, 15, 0, time.UTC)},
+		{"2024-03-01T10:30:15.5Z", time.Date(2024, 3, 1, 10, 30, 15, 500000000, time.UTC)},
+		{"2024-03-01T10:30+02:00", time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{" 2024-03 ", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"01.03.2024", time.Time{}},
+		{"yesterday", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseW3CDate(tt.value); !got.Equal(tt.want) {
+			t.Errorf("parseW3CDate(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF decoder for image.DecodeConfig
+	_ "image/jpeg" // register JPEG decoder for image.DecodeConfig
+	_ "image/png"  // register PNG decoder for image.DecodeConfig
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxSocialImageChecks limits how many preview images are fetched per page
+const maxSocialImageChecks = 3
+
+// OpenGraph holds the Open Graph tags of a page
+type OpenGraph struct {
+	Title       string
+	Description string
+	Type        string
+	URL         string
+	SiteName    string
+	Locale      string
+	Images      []SocialImage
+}
+
+// TwitterCard holds the Twitter Card tags of a page
+type TwitterCard struct {
+	Card        string
+	Site        string
+	Creator     string
+	Title       string
+	Description string
+	Image       string
+	ImageAlt    string
+}
+
+// SocialImage is a preview image declared via og:image or twitter:image.
+// Width and Height are the declared dimensions; the Fetched fields are set
+// once the image has been requested.
+type SocialImage struct {
+	URL          string
+	SecureURL    string
+	Type         string
+	Alt          string
+	Width        int
+	Height       int
+	Checked      bool
+	StatusCode   int
+	ContentType  string
+	Size         int64
+	ActualWidth  int
+	ActualHeight int
+	Error        string
+}
+
+// socialImageCache remembers the preview images fetched during a crawl,
+// which are usually shared by every page of a site
+type socialImageCache struct {
+	entries sync.Map // URL -> SocialImage
+}
+
+// parseSocialMeta records an Open Graph or Twitter Card meta tag. Structured
+// og:image properties apply to the most recently declared image.
+func (r *CrawlResult) parseSocialMeta(key, content, absContent string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	content = strings.TrimSpace(content)
+
+	og := &r.OpenGraph
+	switch key {
+	case "og:title":
+		og.Title = content
+	case "og:description":
+		og.Description = content
+	case "og:type":
+		og.Type = content
+	case "og:url":
+		og.URL = absContent
+	case "og:site_name":
+		og.SiteName = content
+	case "og:locale":
+		og.Locale = content
+	case "og:image", "og:image:url":
+		// og:image:url following og:image describes the same image
+		if key == "og:image:url" && len(og.Images) > 0 && og.Images[len(og.Images)-1].URL == absContent {
+			return
+		}
+		og.Images = append(og.Images, SocialImage{URL: absContent})
+	case "og:image:secure_url", "og:image:type", "og:image:alt", "og:image:width", "og:image:height":
+		if len(og.Images) == 0 {
+			return
+		}
+		img := &og.Images[len(og.Images)-1]
+		switch key {
+		case "og:image:secure_url":
+			img.SecureURL = absContent
+		case "og:image:type":
+			img.Type = content
+		case "og:image:alt":
+			img.Alt = content
+		case "og:image:width":
+			img.Width, _ = strconv.Atoi(content)
+		case "og:image:height":
+			img.Height, _ = strconv.Atoi(content)
+		}
+
+	case "twitter:card":
+		r.TwitterCard.Card = strings.ToLower(content)
+	case "twitter:site":
+		r.TwitterCard.Site = content
+	case "twitter:creator":
+		r.TwitterCard.Creator = content
+	case "twitter:title":
+		r.TwitterCard.Title = content
+	case "twitter:description":
+		r.TwitterCard.Description = content
+	case "twitter:image", "twitter:image:src":
+		r.TwitterCard.Image = absContent
+	case "twitter:image:alt":
+		r.TwitterCard.ImageAlt = content
+	}
+}
+
+// checkSocialImages fetches the declared og:image files and records status,
+// type, size and actual dimensions
+func (c *Crawler) checkSocialImages(ctx context.Context, cache *socialImageCache, result *CrawlResult) {
+	for i := range result.OpenGraph.Images {
+		if i >= maxSocialImageChecks {
+			break
+		}
+		img := &result.OpenGraph.Images[i]
+		if img.URL == "" {
+			continue
+		}
+		checked := c.fetchSocialImage(ctx, cache, img.URL)
+		img.Checked = true
+		img.StatusCode = checked.StatusCode
+		img.ContentType = checked.ContentType
+		img.Size = checked.Size
+		img.ActualWidth = checked.ActualWidth
+		img.ActualHeight = checked.ActualHeight
+		img.Error = checked.Error
+	}
+}
+
+// fetchSocialImage requests an image and decodes its dimensions. Images
+// disallowed by robots.txt are not requested. Results are cached per URL,
+// except for context errors.
+func (c *Crawler) fetchSocialImage(ctx context.Context, cache *socialImageCache, imageURL string) SocialImage {
+	if cached, ok := cache.entries.Load(imageURL); ok {
+		return cached.(SocialImage)
+	}
+
+	checked := SocialImage{URL: imageURL}
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		checked.Error = fmt.Sprintf("invalid image URL: %v", err)
+		return checked
+	}
+	if c.respectRobotsTxt {
+		robots, err := c.robotsFor(ctx, req.URL)
+		if err != nil {
+			checked.Error = fmt.Sprintf("failed to fetch robots.txt: %v", err)
+			return checked
+		}
+		if !robots.IsAllowed(c.userAgent, req.URL) {
+			checked.Error = "blocked by robots.txt"
+			cache.entries.Store(imageURL, checked)
+			return checked
+		}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "image/webp,image/png,image/jpeg,image/*;q=0.8")
+
+	resp, _, err := c.sendCheckRequest(ctx, req)
+	if err != nil {
+		checked.Error = err.Error()
+		if ctx.Err() == nil {
+			cache.entries.Store(imageURL, checked)
+		}
+		return checked
+	}
+	defer resp.Body.Close()
+
+	checked.StatusCode = resp.StatusCode
+	checked.ContentType = resp.Header.Get("Content-Type")
+	checked.Size = resp.ContentLength
+	if resp.StatusCode == http.StatusOK {
+		width, height, err := imageDimensions(resp.Body)
+		if err != nil {
+			checked.Error = fmt.Sprintf("failed to decode image: %v", err)
+		} else {
+			checked.ActualWidth = width
+			checked.ActualHeight = height
+		}
+	}
+
+	cache.entries.Store(imageURL, checked)
+	return checked
+}
+
+// imageDimensions reads the pixel dimensions from the header of a PNG, JPEG,
+// GIF, WebP or AVIF image without decoding the whole file
+func imageDimensions(r io.Reader) (int, int, error) {
+	br := bufio.NewReaderSize(io.LimitReader(r, 1<<20), 4096)
+	// A short image returns fewer bytes and io.EOF, which is fine here
+	header, _ := br.Peek(4096)
+	if len(header) >= 30 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP" {
+		return webpDimensions(header)
+	}
+	if len(header) >= 12 && string(header[4:8]) == "ftyp" && (string(header[8:12]) == "avif" || string(header[8:12]) == "avis") {
+		return avifDimensions(header)
+	}
+	cfg, _, err := image.DecodeConfig(br)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// webpDimensions parses the dimensions from the first chunk of a WebP file
+func webpDimensions(header []byte) (int, int, error) {
+	switch string(header[12:16]) {
+	case "VP8 ":
+		// Lossy: 14-bit width and height after the frame start code
+		width := int(binary.LittleEndian.Uint16(header[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(header[28:30]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// Lossless: 14-bit width-1 and height-1 after the signature byte
+		bits := binary.LittleEndian.Uint32(header[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X":
+		// Extended: 24-bit canvas width-1 and height-1
+		width := int(header[24]) | int(header[25])<<8 | int(header[26])<<16
+		height := int(header[27]) | int(header[28])<<8 | int(header[29])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, fmt.Errorf("unknown WebP chunk %q", header[12:16])
+}
@@ -0,0 +1,258 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+)
+
+// Soft 404 signals
+const (
+	Soft404ErrorPhrase     = "error_phrase"
+	Soft404MatchesNotFound = "matches_not_found_page"
+	Soft404ThinContent     = "thin_content"
+)
+
+const (
+	// soft404MaxErrorWords is the main content length up to which an error
+	// phrase in the title or H1 marks the page as a soft 404. Longer pages
+	// are more likely articles about errors.
+	soft404MaxErrorWords = 300
+	// soft404MinUniqueWords is the number of words a page needs beyond the
+	// not-found template (or in total, if there is no template to compare)
+	soft404MinUniqueWords = 20
+)
+
+// soft404Phrases are German and English error messages of not-found pages
+var soft404Phrases = []string{
+	"nicht gefunden",
+	"existiert nicht",
+	"nicht mehr verfügbar",
+	"nicht mehr erhältlich",
+	"nicht mehr vorhanden",
+	"seite fehlt",
+	"page not found",
+	"not found",
+	"does not exist",
+	"doesn't exist",
+	"no longer available",
+	"cannot be found",
+	"can't be found",
+	"could not be found",
+}
+
+// soft404Code matches a 404 error code in a title or heading
+var soft404Code = regexp.MustCompile(`(^|[^\d])404([^\d]|$)`)
+
+// Soft404 describes why a page that returns 200 looks like an error page
+type Soft404 struct {
+	Detected bool
+	Signals  []string
+	// Phrase is the error message found in the title or H1
+	Phrase string
+	// ProbeURL is a random URL on the same host, requested to learn what the
+	// site returns for pages that do not exist
+	ProbeURL    string
+	ProbeStatus int
+	// Similarity of the main content to the probed not-found page, 0 if the
+	// probe returned no comparable page
+	Similarity float64
+	// UniqueWords counts the distinct main content words that the not-found
+	// page does not contain, or all distinct words without a probe
+	UniqueWords int
+}
+
+// notFoundProbe is the response of a host to a URL that does not exist
+type notFoundProbe struct {
+	url        string
+	statusCode int
+	content    ContentFingerprint
+	words      map[string]bool
+	usable     bool
+}
+
+// notFoundProbeCache remembers one probe per host during a crawl
+type notFoundProbeCache struct {
+	mu      sync.Mutex
+	entries map[string]*notFoundProbeEntry
+}
+
+// notFoundProbeEntry is a cached probe; ready is closed once probe is set
+type notFoundProbeEntry struct {
+	ready chan struct{}
+	probe notFoundProbe
+}
+
+// SetSoft404Probe controls whether a random URL is requested once per host
+// and crawl to compare pages with the site's not-found page
+func (c *Crawler) SetSoft404Probe(enabled bool) {
+	c.soft404Probe = enabled
+}
+
+// checkSoft404 looks for signs that a page returning 200 is an error page:
+// an error message in the title or H1, content matching the page the host
+// returns for a random URL, or almost no content of its own
+func (c *Crawler) checkSoft404(ctx context.Context, cache *notFoundProbeCache, doc *html.Node, result *CrawlResult, pageURL *url.URL) {
+	soft := &Soft404{}
+	result.Soft404 = soft
+
+	heading := ""
+	if len(result.H1Tags) > 0 {
+		heading = result.H1Tags[0]
+	}
+	for _, text := range []string{result.Title, heading} {
+		if phrase := soft404Phrase(text); phrase != "" {
+			soft.Phrase = phrase
+			soft.Signals = append(soft.Signals, Soft404ErrorPhrase)
+			break
+		}
+	}
+
+	words := make(map[string]bool)
+	for _, word := range contentWords(c.mainContent(doc)) {
+		words[word] = true
+	}
+	soft.UniqueWords = len(words)
+
+	// The homepage is not compared, many sites show it for unknown URLs
+	matches := false
+	if c.soft404Probe && pageURL.Path != "" && pageURL.Path != "/" {
+		probe := c.probeNotFound(ctx, cache, pageURL)
+		soft.ProbeURL = probe.url
+		soft.ProbeStatus = probe.statusCode
+		if probe.usable {
+			soft.Similarity = result.Content.Similarity(probe.content)
+			soft.UniqueWords = 0
+			for word := range words {
+				if !probe.words[word] {
+					soft.UniqueWords++
+				}
+			}
+			matches = result.Content.Hash == probe.content.Hash ||
+				(result.Content.Words >= minFingerprintWords && probe.content.Words >= minFingerprintWords &&
+					soft.Similarity >= defaultDuplicateThreshold)
+			if matches {
+				soft.Signals = append(soft.Signals, Soft404MatchesNotFound)
+			}
+		}
+	}
+	if soft.UniqueWords < soft404MinUniqueWords {
+		soft.Signals = append(soft.Signals, Soft404ThinContent)
+	}
+
+	// Thin content alone is common on category and contact pages
+	soft.Detected = matches || (soft.Phrase != "" && result.Content.Words <= soft404MaxErrorWords)
+}
+
+// soft404Phrase returns the error message contained in a title or heading
+func soft404Phrase(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if text == "" {
+		return ""
+	}
+	for _, phrase := range soft404Phrases {
+		if strings.Contains(text, phrase) {
+			return phrase
+		}
+	}
+	if soft404Code.MatchString(text) {
+		return "404"
+	}
+	return ""
+}
+
+// probeNotFound requests a random URL on the host of a page, once per host
+// and crawl
+func (c *Crawler) probeNotFound(ctx context.Context, cache *notFoundProbeCache, pageURL *url.URL) notFoundProbe {
+	host := pageURL.Scheme + "://" + pageURL.Host
+
+	cache.mu.Lock()
+	if cache.entries == nil {
+		cache.entries = make(map[string]*notFoundProbeEntry)
+	}
+	entry, ok := cache.entries[host]
+	if !ok {
+		entry = &notFoundProbeEntry{ready: make(chan struct{})}
+		cache.entries[host] = entry
+	}
+	cache.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+			return entry.probe
+		case <-ctx.Done():
+			return notFoundProbe{}
+		}
+	}
+
+	entry.probe = c.fetchNotFoundProbe(ctx, host)
+	if ctx.Err() != nil {
+		// Do not cache results of cancelled probes
+		cache.mu.Lock()
+		delete(cache.entries, host)
+		cache.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.probe
+}
+
+// fetchNotFoundProbe requests a URL that cannot exist and fingerprints the
+// returned page. Redirects are not followed: the probe records the status
+// the host answers with, and a redirect target is a regular page that is
+// not compared.
+func (c *Crawler) fetchNotFoundProbe(ctx context.Context, host string) notFoundProbe {
+	probe := notFoundProbe{url: fmt.Sprintf("%s/%s-not-found", host, uuid.NewString())}
+	target, err := url.Parse(probe.url)
+	if err != nil {
+		return probe
+	}
+
+	var robots *RobotsTxt
+	if c.respectRobotsTxt {
+		robots, err = c.robotsFor(ctx, target)
+		if err != nil || !robots.IsAllowed(c.userAgent, target) {
+			return probe
+		}
+	}
+	req, err := c.newPageRequest(ctx, probe.url, c.userAgent)
+	if err != nil {
+		return probe
+	}
+	resp, _, err := c.sendPageRequest(ctx, req, robots.CrawlDelay(c.userAgent))
+	if err != nil {
+		return probe
+	}
+	defer resp.Body.Close()
+	probe.statusCode = resp.StatusCode
+	if isRedirectStatus(resp.StatusCode) {
+		return probe
+	}
+
+	page := &CrawlResult{URL: probe.url, FinalURL: probe.url, Headers: make(http.Header)}
+	body, err := readPageBody(resp, page)
+	if err != nil {
+		return probe
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return probe
+	}
+
+	words := contentWords(c.mainContent(doc))
+	probe.content = c.fingerprintContent(doc)
+	probe.words = make(map[string]bool, len(words))
+	for _, word := range words {
+		probe.words[word] = true
+	}
+	probe.usable = probe.content.Words > 0
+	return probe
+}
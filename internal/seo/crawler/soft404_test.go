@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// soft404Template is the page a test site returns with status 200 for
+// URLs that do not exist
+var soft404Template = `<html><head><title>Oops</title></head><body><main><h1>Oops</h1><p>` +
+	strings.Repeat("We are sorry, the page you are looking for has moved somewhere else or maybe it never was here. ", 4) +
+	`</p></main></body></html>`
+
+// soft404Article is a regular page long enough to be compared
+var soft404Article = `<html><head><title>Fire safety</title></head><body><main><h1>Fire safety</h1><p>` +
+	"Smoke detectors belong in every bedroom, hallway and on every floor of a home. Test them once a month, " +
+	"replace the batteries every year and the whole detector after ten years. A fire extinguisher in the kitchen " +
+	"and an escape plan that every member of the household knows complete the basic protection against fire. " +
+	"Practise the escape plan twice a year and agree on a meeting point outside the building." +
+	`</p></main></body></html>`
+
+func TestCrawlSiteSoft404(t *testing.T) {
+	var probes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/":
+			w.Write([]byte(testPage("Home", "/article", "/error", "/gone", "/thin")))
+		case "/article":
+			w.Write([]byte(soft404Article))
+		case "/error":
+			w.Write([]byte(testPage("Seite nicht gefunden")))
+		case "/thin":
+			w.Write([]byte(testPage("Contact")))
+		default:
+			if strings.HasSuffix(r.URL.Path, "-not-found") {
+				probes.Add(1)
+			}
+			// Unknown URLs, including /gone, get the template with 200
+			w.Write([]byte(soft404Template))
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler()
+	c.SetSoft404Probe(true)
+	site, err := c.CrawlSite(context.Background(), srv.URL, SiteCrawlOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("CrawlSite() error = %v", err)
+	}
+	if probes.Load() != 1 {
+		t.Errorf("the not-found page was probed %d times, want once per crawl", probes.Load())
+	}
+
+	tests := []struct {
+		path        string
+		wantDetect  bool
+		wantSignals []string
+	}{
+		{"/article", false, nil},
+		{"/error", true, []string{Soft404ErrorPhrase, Soft404ThinContent}},
+		{"/gone", true, []string{Soft404MatchesNotFound, Soft404ThinContent}},
+		// Thin content alone is not a soft 404
+		{"/thin", false, []string{Soft404ThinContent}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			i := slices.IndexFunc(site.Pages, func(p *CrawlResult) bool { return p.URL == srv.URL+tt.path })
+			if i < 0 {
+				t.Fatalf("%s was not crawled", tt.path)
+			}
+			soft := site.Pages[i].Soft404
+			if soft == nil {
+				t.Fatal("Soft404 is not set")
+			}
+			if soft.Detected != tt.wantDetect || !slices.Equal(soft.Signals, tt.wantSignals) {
+				t.Errorf("Soft404 = %+v, want Detected %v with signals %v", soft, tt.wantDetect, tt.wantSignals)
+			}
+			if soft.ProbeStatus != http.StatusOK || !strings.HasPrefix(soft.ProbeURL, srv.URL+"/") {
+				t.Errorf("probe %s answered %d, want the probe on the crawled host with 200", soft.ProbeURL, soft.ProbeStatus)
+			}
+		})
+	}
+}
+
+func TestSoft404ProbeDoesNotFollowRedirects(t *testing.T) {
+	var home atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/":
+			home.Add(1)
+			w.Write([]byte(testPage("Home", "/article")))
+		case "/article":
+			w.Write([]byte(soft404Article))
+		default:
+			// Unknown URLs are sent to the homepage
+			http.Redirect(w, r, "/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler()
+	c.SetSoft404Probe(true)
+	site, err := c.CrawlSite(context.Background(), srv.URL, SiteCrawlOptions{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("CrawlSite() error = %v", err)
+	}
+	if home.Load() != 1 {
+		t.Errorf("the homepage was requested %d times, want only by the crawl", home.Load())
+	}
+	i := slices.IndexFunc(site.Pages, func(p *CrawlResult) bool { return p.URL == srv.URL+"/article" })
+	if i < 0 {
+		t.Fatal("/article was not crawled")
+	}
+	soft := site.Pages[i].Soft404
+	if soft.Detected || soft.ProbeStatus != http.StatusFound || soft.Similarity != 0 {
+		t.Errorf("Soft404 = %+v, want the unfollowed redirect of the probe and no detection", soft)
+	}
+}
@@ -0,0 +1,278 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Structured data formats
+const (
+	FormatJSONLD    = "json-ld"
+	FormatMicrodata = "microdata"
+	FormatRDFa      = "rdfa"
+)
+
+// StructuredDataItem is a top-level structured data entity found on a page.
+// Properties hold strings, nested property maps or slices of both. Items
+// that could not be parsed carry an Error instead of properties.
+type StructuredDataItem struct {
+	Format     string
+	Types      []string
+	Properties map[string]interface{}
+	Error      string
+}
+
+// Type returns the first schema.org type of the item
+func (i StructuredDataItem) Type() string {
+	if len(i.Types) == 0 {
+		return ""
+	}
+	return i.Types[0]
+}
+
+// extractStructuredData collects all JSON-LD, Microdata and RDFa items of a document
+func (c *Crawler) extractStructuredData(doc *html.Node, baseURL *url.URL) []StructuredDataItem {
+	var items []StructuredDataItem
+
+	var walk func(n *html.Node, inMicrodata, inRDFa bool)
+	walk = func(n *html.Node, inMicrodata, inRDFa bool) {
+		if n.Type == html.ElementNode {
+			if n.Data == "script" && strings.EqualFold(strings.TrimSpace(c.getAttr(n, "type")), "application/ld+json") {
+				items = append(items, parseJSONLD(c.extractText(n))...)
+			}
+			// Top-level Microdata items are itemscopes that are not a property of another item
+			if c.hasAttr(n, "itemscope") && !(inMicrodata && c.hasAttr(n, "itemprop")) {
+				props := make(map[string]interface{})
+				c.collectMicrodata(n, props, baseURL)
+				items = append(items, StructuredDataItem{
+					Format:     FormatMicrodata,
+					Types:      schemaTypes(strings.Fields(c.getAttr(n, "itemtype"))),
+					Properties: props,
+				})
+				inMicrodata = true
+			}
+			if c.hasAttr(n, "typeof") && !(inRDFa && c.hasAttr(n, "property")) {
+				props := make(map[string]interface{})
+				c.collectRDFa(n, props, baseURL)
+				items = append(items, StructuredDataItem{
+					Format:     FormatRDFa,
+					Types:      schemaTypes(strings.Fields(c.getAttr(n, "typeof"))),
+					Properties: props,
+				})
+				inRDFa = true
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inMicrodata, inRDFa)
+		}
+	}
+	walk(doc, false, false)
+
+	return items
+}
+
+// parseJSONLD parses the content of a JSON-LD script, expanding arrays and @graph
+func parseJSONLD(content string) []StructuredDataItem {
+	var data interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &data); err != nil {
+		return []StructuredDataItem{{Format: FormatJSONLD, Error: "invalid JSON-LD: " + err.Error()}}
+	}
+
+	var items []StructuredDataItem
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch value := v.(type) {
+		case []interface{}:
+			for _, entry := range value {
+				collect(entry)
+			}
+		case map[string]interface{}:
+			if graph, ok := value["@graph"]; ok {
+				collect(graph)
+				return
+			}
+			items = append(items, StructuredDataItem{
+				Format:     FormatJSONLD,
+				Types:      schemaTypes(jsonLDTypes(value["@type"])),
+				Properties: value,
+			})
+		}
+	}
+	collect(data)
+
+	if len(items) == 0 {
+		return []StructuredDataItem{{Format: FormatJSONLD, Error: "JSON-LD contains no entities"}}
+	}
+	return items
+}
+
+// jsonLDTypes returns the values of an @type property
+func jsonLDTypes(v interface{}) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var types []string
+		for _, t := range value {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// schemaTypes strips the schema.org namespace from type names
+func schemaTypes(types []string) []string {
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		t = strings.TrimPrefix(t, "schema:")
+		if idx := strings.Index(t, "schema.org/"); idx >= 0 {
+			t = t[idx+len("schema.org/"):]
+		}
+		if t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// collectMicrodata collects the itemprop values below an itemscope element
+func (c *Crawler) collectMicrodata(scope *html.Node, props map[string]interface{}, baseURL *url.URL) {
+	for child := scope.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		names := strings.Fields(c.getAttr(child, "itemprop"))
+		if len(names) == 0 {
+			if !c.hasAttr(child, "itemscope") {
+				c.collectMicrodata(child, props, baseURL)
+			}
+			continue
+		}
+
+		var value interface{}
+		if c.hasAttr(child, "itemscope") {
+			nested := make(map[string]interface{})
+			if types := schemaTypes(strings.Fields(c.getAttr(child, "itemtype"))); len(types) > 0 {
+				nested["@type"] = types[0]
+			}
+			c.collectMicrodata(child, nested, baseURL)
+			value = nested
+		} else {
+			value = c.microdataValue(child, baseURL)
+			c.collectMicrodata(child, props, baseURL)
+		}
+		for _, name := range names {
+			addProperty(props, name, value)
+		}
+	}
+}
+
+// microdataValue returns the value of an itemprop element per the HTML spec
+func (c *Crawler) microdataValue(n *html.Node, baseURL *url.URL) string {
+	switch n.Data {
+	case "meta":
+		return c.getAttr(n, "content")
+	case "a", "link", "area":
+		return c.makeAbsolute(c.getAttr(n, "href"), baseURL)
+	case "img", "audio", "video", "source", "iframe", "embed", "track":
+		return c.makeAbsolute(c.getAttr(n, "src"), baseURL)
+	case "object":
+		return c.makeAbsolute(c.getAttr(n, "data"), baseURL)
+	case "time":
+		if c.hasAttr(n, "datetime") {
+			return c.getAttr(n, "datetime")
+		}
+	case "data", "meter":
+		return c.getAttr(n, "value")
+	}
+	if c.hasAttr(n, "content") {
+		return c.getAttr(n, "content")
+	}
+	return strings.Join(strings.Fields(c.extractText(n)), " ")
+}
+
+// collectRDFa collects the property values below a typeof element
+func (c *Crawler) collectRDFa(scope *html.Node, props map[string]interface{}, baseURL *url.URL) {
+	for child := scope.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		names := strings.Fields(c.getAttr(child, "property"))
+		if len(names) == 0 {
+			if !c.hasAttr(child, "typeof") {
+				c.collectRDFa(child, props, baseURL)
+			}
+			continue
+		}
+
+		var value interface{}
+		if c.hasAttr(child, "typeof") {
+			nested := make(map[string]interface{})
+			if types := schemaTypes(strings.Fields(c.getAttr(child, "typeof"))); len(types) > 0 {
+				nested["@type"] = types[0]
+			}
+			c.collectRDFa(child, nested, baseURL)
+			value = nested
+		} else {
+			value = c.rdfaValue(child, baseURL)
+			c.collectRDFa(child, props, baseURL)
+		}
+		for _, name := range names {
+			addProperty(props, rdfaPropertyName(name), value)
+		}
+	}
+}
+
+// rdfaValue returns the value of an RDFa property element
+func (c *Crawler) rdfaValue(n *html.Node, baseURL *url.URL) string {
+	switch {
+	case c.hasAttr(n, "content"):
+		return c.getAttr(n, "content")
+	case c.hasAttr(n, "resource"):
+		return c.makeAbsolute(c.getAttr(n, "resource"), baseURL)
+	case c.hasAttr(n, "href"):
+		return c.makeAbsolute(c.getAttr(n, "href"), baseURL)
+	case c.hasAttr(n, "src"):
+		return c.makeAbsolute(c.getAttr(n, "src"), baseURL)
+	}
+	return strings.Join(strings.Fields(c.extractText(n)), " ")
+}
+
+// rdfaPropertyName strips the schema.org prefix from an RDFa property name
+func rdfaPropertyName(name string) string {
+	if types := schemaTypes([]string{name}); len(types) > 0 {
+		return types[0]
+	}
+	return name
+}
+
+// addProperty stores a value, turning repeated properties into a slice
+func addProperty(props map[string]interface{}, name string, value interface{}) {
+	existing, ok := props[name]
+	if !ok {
+		props[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		props[name] = append(list, value)
+		return
+	}
+	props[name] = []interface{}{existing, value}
+}
+
+// hasAttr reports whether a node has an attribute, even an empty one
+func (c *Crawler) hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
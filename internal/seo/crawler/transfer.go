@@ -0,0 +1,197 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transfer limits
+const (
+	// maxPageBytes is the amount of HTML Googlebot processes per page
+	maxPageBytes     = 15 * 1024 * 1024
+	maxAssetBytes    = 10 * 1024 * 1024
+	maxAssetsPerPage = 100
+	assetConcurrency = 4
+)
+
+// Transfer describes how a page or asset was delivered
+type Transfer struct {
+	Protocol        string
+	TTFBMs          int64
+	DownloadMs      int64
+	TransferSize    int64 // bytes received, possibly compressed
+	DecodedSize     int64 // bytes after decompression, 0 if unknown
+	ContentType     string
+	ContentEncoding string
+	CacheControl    string
+	Expires         string
+	ETag            string
+	LastModified    string
+}
+
+// assetCache remembers the assets fetched during a crawl, which are usually
+// shared by every page of a site
+type assetCache struct {
+	entries sync.Map // URL -> Asset
+}
+
+// SetCheckAssets controls whether the subresources of a page are fetched to
+// measure their size, compression and caching
+func (c *Crawler) SetCheckAssets(check bool) {
+	c.checkAssets = check
+}
+
+// recordTransfer copies the delivery headers of a response
+func (t *Transfer) recordTransfer(resp *http.Response) {
+	t.Protocol = resp.Proto
+	t.ContentType = resp.Header.Get("Content-Type")
+	t.ContentEncoding = strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	t.CacheControl = resp.Header.Get("Cache-Control")
+	t.Expires = resp.Header.Get("Expires")
+	t.ETag = resp.Header.Get("ETag")
+	t.LastModified = resp.Header.Get("Last-Modified")
+}
+
+// readPageBody downloads and decompresses a page body, recording the
+// transfer in result.Transfer
+func readPageBody(resp *http.Response, result *CrawlResult) ([]byte, error) {
+	transfer := &result.Transfer
+	transfer.recordTransfer(resp)
+
+	start := time.Now()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	transfer.DownloadMs = time.Since(start).Milliseconds()
+	transfer.TransferSize = int64(len(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	body, err := decodeBody(raw, transfer.ContentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s body: %w", transfer.ContentEncoding, err)
+	}
+	if len(body) > maxPageBytes {
+		body = body[:maxPageBytes]
+		result.Errors = append(result.Errors, fmt.Sprintf("page is larger than %d bytes and was truncated", maxPageBytes))
+	}
+	transfer.DecodedSize = int64(len(body))
+	result.ResponseSize = transfer.DecodedSize
+	return body, nil
+}
+
+// decodeBody decompresses a body with the gzip and deflate encodings that
+// page requests accept
+func decodeBody(raw []byte, encoding string) ([]byte, error) {
+	var reader io.Reader
+	switch encoding {
+	case "", "identity":
+		return raw, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		reader = zr
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send raw deflate
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			reader = flate.NewReader(bytes.NewReader(raw))
+		} else {
+			reader = zr
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding")
+	}
+	return io.ReadAll(io.LimitReader(reader, maxPageBytes+1))
+}
+
+// fetchAsset requests an asset and measures its delivery. Assets disallowed
+// by robots.txt are not requested. Results are cached per URL, except for
+// context errors.
+func (c *Crawler) fetchAsset(ctx context.Context, cache *assetCache, assetURL, kind string) Asset {
+	if cached, ok := cache.entries.Load(assetURL); ok {
+		return cached.(Asset)
+	}
+
+	asset := Asset{URL: assetURL}
+	req, err := http.NewRequestWithContext(ctx, "GET", assetURL, nil)
+	if err != nil {
+		asset.Error = fmt.Sprintf("invalid asset URL: %v", err)
+		return asset
+	}
+	if c.respectRobotsTxt {
+		robots, err := c.robotsFor(ctx, req.URL)
+		if err != nil {
+			if ctx.Err() == nil {
+				asset.Error = fmt.Sprintf("failed to fetch robots.txt: %v", err)
+			}
+			return asset
+		}
+		if !robots.IsAllowed(c.userAgent, req.URL) {
+			asset.Error = "blocked by robots.txt"
+			cache.entries.Store(assetURL, asset)
+			return asset
+		}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	switch kind {
+	case AssetImage:
+		// Announce modern formats like a browser so negotiated WebP/AVIF is detected
+		req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/*,*/*;q=0.8")
+	case AssetStylesheet:
+		req.Header.Set("Accept", "text/css,*/*;q=0.1")
+	default:
+		req.Header.Set("Accept", "*/*")
+	}
+	// Setting the header disables transparent decompression, so the
+	// encoding and the transferred size stay visible
+	req.Header.Set("Accept-Encoding", "br, gzip, deflate, zstd")
+
+	resp, latency, err := c.sendCheckRequest(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return asset
+		}
+		asset.Error = err.Error()
+		cache.entries.Store(assetURL, asset)
+		return asset
+	}
+	defer resp.Body.Close()
+
+	asset.StatusCode = resp.StatusCode
+	asset.Transfer.TTFBMs = latency.Milliseconds()
+	asset.Transfer.recordTransfer(resp)
+
+	start := time.Now()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetBytes))
+	asset.Transfer.DownloadMs = time.Since(start).Milliseconds()
+	asset.Transfer.TransferSize = int64(len(raw))
+	if err != nil {
+		if ctx.Err() != nil {
+			return asset
+		}
+		asset.Error = fmt.Sprintf("failed to read asset: %v", err)
+	} else if body, err := decodeBody(raw, asset.Transfer.ContentEncoding); err == nil {
+		// Brotli and zstd cannot be decoded, their decoded size stays unknown
+		asset.Transfer.DecodedSize = int64(len(body))
+		if strings.Contains(asset.Transfer.ContentType, "css") {
+			asset.fonts = cssFontURLs(string(body), resp.Request.URL)
+		}
+		if kind == AssetImage && resp.StatusCode == http.StatusOK {
+			asset.Width, asset.Height, _ = imageDimensions(bytes.NewReader(body))
+		}
+	}
+
+	cache.entries.Store(assetURL, asset)
+	return asset
+}
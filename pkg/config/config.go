@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -67,6 +68,20 @@ type SEOConfig struct {
 	RespectRobotsTxt   bool
 	MaxConcurrentCrawls int
 	CrawlDelay         time.Duration
+	MaxRetries         int
+	MobileParity       bool
+	CheckAssets        bool
+	Soft404Probe       bool
+	CheckpointDir      string
+	ProxyURL           string
+	Proxies            map[string]string
+	DNSServer          string
+	SourceIP           string
+	MaxConnsPerHost    int
+	MaxIdleConnsPerHost int
+	TLSCAFile          string
+	TLSInsecure        bool
+	AllowInsecureTLS   bool
 }
 
 // Load loads configuration from environment variables
@@ -117,6 +132,20 @@ func Load() *Config {
 			RespectRobotsTxt:   getBoolEnv("SEO_RESPECT_ROBOTS_TXT", true),
 			MaxConcurrentCrawls: getIntEnv("SEO_MAX_CONCURRENT_CRAWLS", 5),
 			CrawlDelay:         getDurationEnv("SEO_CRAWL_DELAY", 1*time.Second),
+			MaxRetries:         getIntEnv("SEO_MAX_RETRIES", 3),
+			MobileParity:       getBoolEnv("SEO_MOBILE_PARITY", false),
+			CheckAssets:        getBoolEnv("SEO_CHECK_ASSETS", true),
+			Soft404Probe:       getBoolEnv("SEO_SOFT404_PROBE", true),
+			CheckpointDir:      getEnv("SEO_CHECKPOINT_DIR", ""),
+			ProxyURL:           getEnv("SEO_PROXY_URL", ""),
+			Proxies:            getMapEnv("SEO_PROXIES"),
+			DNSServer:          getEnv("SEO_DNS_SERVER", ""),
+			SourceIP:           getEnv("SEO_SOURCE_IP", ""),
+			MaxConnsPerHost:    getIntEnv("SEO_MAX_CONNS_PER_HOST", 0),
+			MaxIdleConnsPerHost: getIntEnv("SEO_MAX_IDLE_CONNS_PER_HOST", 10),
+			TLSCAFile:          getEnv("SEO_TLS_CA_FILE", ""),
+			TLSInsecure:        getBoolEnv("SEO_TLS_INSECURE", false),
+			AllowInsecureTLS:   getBoolEnv("SEO_ALLOW_INSECURE_TLS", false),
 		},
 	}
 }
@@ -155,3 +184,14 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getMapEnv(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" {
+			values[name] = value
+		}
+	}
+	return values
+}